@@ -0,0 +1,199 @@
+// Package achievement awards badges to players from the events of their games.
+//
+// The engine records player statistics from the events and every rule watches
+// one statistic, the badge of the rule is awarded when the statistic reaches
+// the goal. The badges are stored per player so each badge is awarded once.
+// Placement badges are awarded to the top players of a channel when a season ends.
+package achievement
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+)
+
+// Statistics recorded from the game events
+const (
+	StatCorrect   = "correct"    // correct answers
+	StatTopAnswer = "top_answer" // correct answers with the highest score of the board
+	StatSweep     = "sweep"      // boards that were answered by the player alone
+	StatStreak    = "streak"     // consecutive days with a finished game
+)
+
+// Badge is an achievement of a player
+type Badge struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Rule awards the badge when the statistic of a player reaches the goal
+type Rule struct {
+	Badge Badge
+	Stat  string
+	Goal  int64
+}
+
+// DefaultRules are the available achievements
+var DefaultRules = []Rule{
+	{Badge{"first_answer", "Langkah Pertama", "Jawaban benar pertama"}, StatCorrect, 1},
+	{Badge{"sweep", "Sapu Bersih", "Menjawab semua jawaban satu ronde sendirian"}, StatSweep, 1},
+	{Badge{"streak_10", "Tak Pernah Absen", "Bermain 10 hari berturut-turut"}, StatStreak, 10},
+	{Badge{"top_answer_50", "Kata Survei", "Menebak jawaban teratas 50 kali"}, StatTopAnswer, 50},
+}
+
+// Store keeps the statistics and the badges of players, it is implemented by repo.DefaultDB
+type Store interface {
+	IncPlayerStats(playerID model.PlayerID, key string) (int64, error)
+	PlayStreak(playerID model.PlayerID, day, yesterday string) (streak int64, err error)
+	AddBadge(playerID model.PlayerID, badge string, at time.Time) (added bool, err error)
+}
+
+// Award is a badge earned by a player in a game
+type Award struct {
+	ChanID string
+	GameID int64
+	Player model.Player
+	Badge  Badge
+}
+
+// Engine evaluates game events against the rules
+type Engine struct {
+	Rules    []Rule
+	Store    Store
+	Location *time.Location // time zone of the days of a streak
+}
+
+// New creates an engine with the default rules
+func New(store Store, loc *time.Location) *Engine {
+	return &Engine{Rules: DefaultRules, Store: store, Location: loc}
+}
+
+// SeasonPlaces is the number of places of a season ranking that get a badge
+var SeasonPlaces = 3
+
+// SeasonBadge is the badge of the place, starting at 1, in the ranking of a channel in a season.
+// The channel is part of the id so a player can earn a badge in every channel
+func SeasonBadge(chanID string, season, place int) Badge {
+	return Badge{
+		ID:          fmt.Sprintf("season_%d_%d_%s", season, place, chanID),
+		Name:        fmt.Sprintf(fam100.T("Juara %d Season %d"), place, season),
+		Description: fmt.Sprintf(fam100.T("Peringkat %d di grup pada akhir season %d"), place, season),
+	}
+}
+
+// Badge returns the badge with the id
+func (e *Engine) Badge(id string) (Badge, bool) {
+	for _, rule := range e.Rules {
+		if rule.Badge.ID == id {
+			return rule.Badge, true
+		}
+	}
+	var season, place int
+	var chanID string
+	if n, _ := fmt.Sscanf(id, "season_%d_%d_%s", &season, &place, &chanID); n == 3 {
+		return SeasonBadge(chanID, season, place), true
+	}
+
+	return Badge{}, false
+}
+
+// Handle records the statistics of the event and returns the badges earned with it.
+// Events that are not relevant to any statistic are ignored
+func (e *Engine) Handle(ev fam100.Event, now time.Time) ([]Award, error) {
+	var awards []Award
+	var err error
+
+	switch msg := ev.(type) {
+	case fam100.AnswerMessage:
+		inc := func(stat string) {
+			value, incErr := e.Store.IncPlayerStats(msg.Player.ID, stat)
+			if incErr != nil {
+				err = errors.Wrapf(incErr, "failed to record %s of %s", stat, msg.Player.ID)
+				return
+			}
+			earned, checkErr := e.check(msg.ChanID, msg.GameID, msg.Player, stat, value, now)
+			if checkErr != nil {
+				err = checkErr
+			}
+			awards = append(awards, earned...)
+		}
+		inc(StatCorrect)
+		if msg.Top {
+			inc(StatTopAnswer)
+		}
+		if msg.Board > 1 && msg.Answered == msg.Board {
+			inc(StatSweep)
+		}
+
+	case fam100.RankMessage:
+		if !msg.Final {
+			break
+		}
+		day := fam100.Day(now, e.Location)
+		yesterday := fam100.Day(now.In(e.Location).AddDate(0, 0, -1), e.Location)
+		for _, ps := range msg.Rank {
+			streak, streakErr := e.Store.PlayStreak(ps.PlayerID, day, yesterday)
+			if streakErr != nil {
+				err = errors.Wrapf(streakErr, "failed to record streak of %s", ps.PlayerID)
+				continue
+			}
+			p := model.Player{ID: ps.PlayerID, Name: ps.Name}
+			earned, checkErr := e.check(msg.ChanID, msg.GameID, p, StatStreak, streak, now)
+			if checkErr != nil {
+				err = checkErr
+			}
+			awards = append(awards, earned...)
+		}
+	}
+
+	return awards, err
+}
+
+// Season awards the placement badges of a season to the top players of the archived
+// ranking of a channel. Tied players share the place
+func (e *Engine) Season(chanID string, season int, rank model.Rank, now time.Time) ([]Award, error) {
+	var awards []Award
+	var err error
+	for _, ps := range rank {
+		if ps.Position < 1 || ps.Position > SeasonPlaces {
+			continue
+		}
+		badge := SeasonBadge(chanID, season, ps.Position)
+		added, addErr := e.Store.AddBadge(ps.PlayerID, badge.ID, now)
+		if addErr != nil {
+			err = errors.Wrapf(addErr, "failed to add badge %s to %s", badge.ID, ps.PlayerID)
+			continue
+		}
+		if added {
+			awards = append(awards, Award{ChanID: chanID, Player: model.Player{ID: ps.PlayerID, Name: ps.Name}, Badge: badge})
+		}
+	}
+
+	return awards, err
+}
+
+// check awards the badges of the rules whose goal is reached by the new value of the
+// statistic. A statistic can skip the goal, AddBadge only awards a badge once
+func (e *Engine) check(chanID string, gameID int64, p model.Player, stat string, value int64, now time.Time) ([]Award, error) {
+	var awards []Award
+	var err error
+	for _, rule := range e.Rules {
+		if rule.Stat != stat || value < rule.Goal {
+			continue
+		}
+		added, addErr := e.Store.AddBadge(p.ID, rule.Badge.ID, now)
+		if addErr != nil {
+			err = errors.Wrapf(addErr, "failed to add badge %s to %s", rule.Badge.ID, p.ID)
+			continue
+		}
+		if added {
+			awards = append(awards, Award{ChanID: chanID, GameID: gameID, Player: p, Badge: rule.Badge})
+		}
+	}
+
+	return awards, err
+}
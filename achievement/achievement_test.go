@@ -0,0 +1,156 @@
+package achievement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
+)
+
+var foo = model.Player{ID: "1", Name: "foo"}
+
+func badgeIDs(awards []Award) []string {
+	var ids []string
+	for _, a := range awards {
+		ids = append(ids, a.Badge.ID)
+	}
+	return ids
+}
+
+func TestAnswerBadges(t *testing.T) {
+	e := New(new(repo.MemoryDB), time.UTC)
+	now := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		msg   fam100.AnswerMessage
+		award []string
+	}{
+		{"first answer", fam100.AnswerMessage{Player: foo, Board: 5, Answered: 1}, []string{"first_answer"}},
+		{"second answer", fam100.AnswerMessage{Player: foo, Board: 5, Answered: 2}, nil},
+		{"sweep", fam100.AnswerMessage{Player: foo, Board: 3, Answered: 3}, []string{"sweep"}},
+		{"second sweep", fam100.AnswerMessage{Player: foo, Board: 3, Answered: 3}, nil},
+		{"single answer board", fam100.AnswerMessage{Player: foo, Board: 1, Answered: 1}, nil},
+	}
+	for _, tt := range tests {
+		awards, err := e.Handle(tt.msg, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, got := tt.award, badgeIDs(awards); len(want) != len(got) || (len(want) > 0 && want[0] != got[0]) {
+			t.Errorf("%s: want %v got %v", tt.name, want, got)
+		}
+	}
+}
+
+func TestTopAnswerBadge(t *testing.T) {
+	e := New(new(repo.MemoryDB), time.UTC)
+	now := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var earned []string
+	for i := 1; i <= 60; i++ {
+		awards, err := e.Handle(fam100.AnswerMessage{Player: foo, Top: true, Board: 5, Answered: 1}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range badgeIDs(awards) {
+			if id == "top_answer_50" && i != 50 {
+				t.Errorf("top answer badge awarded at answer %d", i)
+			}
+		}
+		earned = append(earned, badgeIDs(awards)...)
+	}
+	if want, got := 2, len(earned); want != got {
+		t.Errorf("badges, want %d got %d %v", want, got, earned)
+	}
+}
+
+func TestBadgeAfterGoal(t *testing.T) {
+	e := New(new(repo.MemoryDB), time.UTC)
+	now := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// a statistic that skipped the goal still earns the badge, only once
+	awards, err := e.check("1", 1, foo, StatTopAnswer, 51, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []string{"top_answer_50"}, badgeIDs(awards); len(got) != 1 || want[0] != got[0] {
+		t.Errorf("want %v got %v", want, got)
+	}
+	if awards, _ := e.check("1", 1, foo, StatTopAnswer, 52, now); len(awards) > 0 {
+		t.Errorf("badge should be awarded once, got %v", badgeIDs(awards))
+	}
+}
+
+func TestStreakBadge(t *testing.T) {
+	e := New(new(repo.MemoryDB), time.UTC)
+	start := time.Date(2017, 1, 1, 22, 0, 0, 0, time.UTC)
+	final := fam100.RankMessage{Final: true, Rank: model.Rank{{PlayerID: foo.ID, Name: foo.Name, Score: 10}}}
+
+	play := func(now time.Time) []string {
+		awards, err := e.Handle(final, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return badgeIDs(awards)
+	}
+
+	// a gap resets the streak
+	play(start)
+	play(start.AddDate(0, 0, 2))
+	for i := 3; i < 11; i++ {
+		if got := play(start.AddDate(0, 0, i)); len(got) > 0 {
+			t.Fatalf("day %d: unexpected badge %v", i, got)
+		}
+	}
+	// playing twice a day keeps the streak
+	play(start.AddDate(0, 0, 10).Add(time.Hour))
+	if want, got := "streak_10", play(start.AddDate(0, 0, 11)); len(got) != 1 || got[0] != want {
+		t.Errorf("10 days streak, want %s got %v", want, got)
+	}
+}
+
+func TestSeasonBadges(t *testing.T) {
+	e := New(new(repo.MemoryDB), time.UTC)
+	now := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	rank := model.Rank{
+		{PlayerID: "1", Score: 30},
+		{PlayerID: "2", Score: 20},
+		{PlayerID: "3", Score: 20},
+		{PlayerID: "4", Score: 10},
+	}
+	rank.Sort(model.StandardRank)
+	awards, err := e.Season("c1", 2, rank, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"season_2_1_c1", "season_2_2_c1", "season_2_2_c1"}
+	if got := badgeIDs(awards); len(want) != len(got) {
+		t.Fatalf("awards want %v got %v", want, got)
+	}
+	for i, id := range badgeIDs(awards) {
+		if want[i] != id {
+			t.Errorf("awards[%d] want %s got %s", i, want[i], id)
+		}
+	}
+
+	badge, ok := e.Badge("season_2_1_-100123")
+	if !ok {
+		t.Fatal("season badge not found")
+	}
+	if want, got := SeasonBadge("-100123", 2, 1), badge; want != got {
+		t.Errorf("badge want %+v got %+v", want, got)
+	}
+
+	// the same place in another channel is another badge
+	awards, err = e.Season("c2", 2, rank, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 3, len(awards); want != got {
+		t.Errorf("awards in another channel want %d got %d", want, got)
+	}
+}
@@ -0,0 +1,221 @@
+package fam100
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/qna"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// CheckpointKind is the repo checkpoint kind of a running game
+const CheckpointKind = "game"
+
+// Checkpoint is the state of a running game that is used to resume it after restart
+type Checkpoint struct {
+	GameID           int64                           `json:"gameID"`
+	ChanID           string                          `json:"chanID"`
+	ChanName         string                          `json:"chanName"`
+	Seed             int64                           `json:"seed"`
+	TotalRoundPlayed int                             `json:"totalRoundPlayed"`
+	Round            int                             `json:"round"` // round to resume
+	Finalizing       bool                            `json:"finalizing,omitempty"`
+	Players          map[model.PlayerID]model.Player `json:"players"`
+	Teams            map[model.PlayerID]string       `json:"teams"`
+	Rank             model.Rank                      `json:"rank"`
+	TeamRank         model.TeamRank                  `json:"teamRank"`
+	Skipped          int                             `json:"skipped"`
+	FirstAnswerAt    map[model.PlayerID]time.Time    `json:"firstAnswerAt"`
+	AnswerCount      map[model.PlayerID]int          `json:"answerCount"`
+	LastAnswerAt     map[model.PlayerID]time.Time    `json:"lastAnswerAt"`
+	Daily            string                          `json:"daily,omitempty"`
+	FixedSequence    bool                            `json:"fixedSequence,omitempty"`
+	Participants     map[model.PlayerID]model.Player `json:"participants,omitempty"`
+
+	// last finished round, shown with the next rank
+	Breakdown []ScoreDetail `json:"breakdown,omitempty"`
+	Recap     RoundRecap    `json:"recap"`
+
+	// state of the round in progress, QuestionID is 0 if the round has not started
+	QuestionID int              `json:"questionID"`
+	StartedAt  time.Time        `json:"startedAt"`
+	EndAt      time.Time        `json:"endAt"`
+	Correct    []model.PlayerID `json:"correct"`
+	AnsweredAt []time.Time      `json:"answeredAt"`
+	Hints      []int            `json:"hints"`
+
+	SavedAt time.Time `json:"savedAt"`
+}
+
+// saveCheckpoint stores the game state. r is the round in progress or nil at round boundary
+func (g *Game) saveCheckpoint(round int, r *round) {
+	if g.Practice || g.lostLease() {
+		// practice is not worth resuming
+		return
+	}
+	cp := Checkpoint{
+		GameID:           g.id,
+		ChanID:           g.ChanID,
+		ChanName:         g.chanName,
+		Seed:             g.seed,
+		TotalRoundPlayed: g.totalRoundPlayed,
+		Round:            round,
+		Players:          g.players,
+		Teams:            g.teams,
+		Rank:             g.rank,
+		TeamRank:         g.teamRank,
+		Skipped:          g.skipped,
+		FirstAnswerAt:    g.firstAnswerAt,
+		AnswerCount:      g.answerCount,
+		LastAnswerAt:     g.lastAnswerAt,
+		Daily:            g.Daily,
+		FixedSequence:    g.fixedSequence,
+		Participants:     g.participants,
+		Breakdown:        g.roundDetails,
+		Recap:            g.roundRecap,
+		SavedAt:          time.Now(),
+	}
+	if g.finalizing {
+		// sudden death is played again from the start
+		cp.Round = RoundPerGame
+		cp.Finalizing = true
+	}
+	if r != nil && !r.suddenDeath {
+		cp.QuestionID = r.q.ID
+		cp.StartedAt = r.startedAt
+		cp.EndAt = r.endAt
+		cp.Correct = r.correct
+		cp.AnsweredAt = r.answeredAt
+		cp.Hints = r.hints
+	}
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		log.Error("failed to encode checkpoint", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Error(err))
+		return
+	}
+	if err := repo.DefaultDB.SaveCheckpoint(CheckpointKind, g.ChanID, data); err != nil {
+		log.Error("failed to save checkpoint", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Error(err))
+	}
+}
+
+func (g *Game) deleteCheckpoint() {
+	if g.lostLease() {
+		// the checkpoint belongs to the new owner
+		return
+	}
+	if err := repo.DefaultDB.DeleteCheckpoint(CheckpointKind, g.ChanID); err != nil {
+		log.Error("failed to delete checkpoint", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Error(err))
+	}
+}
+
+// LoadCheckpoints returns checkpoint of all games that were running
+func LoadCheckpoints() ([]Checkpoint, error) {
+	raw, err := repo.DefaultDB.Checkpoints(CheckpointKind)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := make([]Checkpoint, 0, len(raw))
+	for chanID, data := range raw {
+		var cp Checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			log.Error("invalid checkpoint", zap.String("chanID", chanID), zap.Error(err))
+			continue
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+
+	return checkpoints, nil
+}
+
+// LoadCheckpoint returns the checkpoint of the game of a channel, found is false if there is none
+func LoadCheckpoint(chanID string) (cp Checkpoint, found bool, err error) {
+	data, err := repo.DefaultDB.Checkpoint(CheckpointKind, chanID)
+	if err != nil || data == nil {
+		return cp, false, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, false, errors.Wrap(err, "invalid checkpoint")
+	}
+
+	return cp, true, nil
+}
+
+// DeleteCheckpoint removes checkpoint of a game that won't be resumed
+func DeleteCheckpoint(chanID string) error {
+	return repo.DefaultDB.DeleteCheckpoint(CheckpointKind, chanID)
+}
+
+// RestoreGame creates a game from a checkpoint, call Resume to continue playing
+func RestoreGame(cp Checkpoint, in chan Message, questionDB qna.Provider) (*Game, error) {
+	if cp.Round < 1 || cp.Round > RoundPerGame {
+		return nil, errors.Errorf("invalid checkpoint round %d", cp.Round)
+	}
+
+	g := &Game{
+		id:               cp.GameID,
+		ChanID:           cp.ChanID,
+		chanName:         cp.ChanName,
+		State:            Created,
+		seed:             cp.Seed,
+		totalRoundPlayed: cp.TotalRoundPlayed,
+		players:          cp.Players,
+		teams:            cp.Teams,
+		rank:             cp.Rank,
+		skipped:          cp.Skipped,
+		firstAnswerAt:    cp.FirstAnswerAt,
+		answerCount:      cp.AnswerCount,
+		lastAnswerAt:     cp.LastAnswerAt,
+		participants:     cp.Participants,
+		roundDetails:     cp.Breakdown,
+		roundRecap:       cp.Recap,
+		Daily:            cp.Daily,
+		fixedSequence:    cp.FixedSequence || cp.Daily != "",
+		In:               in,
+		questionDB:       questionDB,
+		finalizing:       cp.Finalizing,
+		resume:           &cp,
+	}
+	if g.players == nil {
+		g.players = make(map[model.PlayerID]model.Player)
+	}
+	if g.teams == nil {
+		g.teams = make(map[model.PlayerID]string)
+	}
+	if g.firstAnswerAt == nil {
+		g.firstAnswerAt = make(map[model.PlayerID]time.Time)
+	}
+	if g.answerCount == nil {
+		g.answerCount = make(map[model.PlayerID]int)
+	}
+	if g.lastAnswerAt == nil {
+		g.lastAnswerAt = make(map[model.PlayerID]time.Time)
+	}
+	g.subscribeDefaults()
+
+	return g, nil
+}
+
+// Resume continues a restored game from the round in its checkpoint
+func (g *Game) Resume() {
+	g.State = Started
+	g.setup()
+	g.teamRank = g.resume.TeamRank
+	round := g.resume.Round
+	if g.finalizing {
+		// all rounds were played, only the final rank is left
+		round = RoundPerGame + 1
+	}
+	g.openJournal()
+	log.Info("Game resumed", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int("round", round))
+
+	go func() {
+		defer g.closeJournal()
+		defer g.closeSubscriptions()
+		g.play(round)
+	}()
+}
@@ -0,0 +1,186 @@
+package fam100
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/qna"
+	"github.com/yulrizka/fam100/repo"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	oDB, oDuration := repo.DefaultDB, RoundDuration
+	defer func() { repo.DefaultDB, RoundDuration = oDB, oDuration }()
+	repo.DefaultDB = new(repo.MemoryDB)
+	RoundDuration = time.Second
+
+	qnaDB, err := qna.NewText("qna/questions.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// apa yang orang katakan sambil memegang pipi orang lain*33:gemes*20:lucu*12:sayang*...
+	q := testQuestion(t, "1")
+	players := map[model.PlayerID]model.Player{"1": {ID: "1", Name: "foo"}}
+	r, err := newRound(q, players)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.state = RoundStarted
+	r.answer(players["1"], "lucu", time.Now())
+
+	g := &Game{id: 1, ChanID: "1", chanName: "chan", totalRoundPlayed: 5, players: players}
+	g.saveCheckpoint(2, r)
+
+	checkpoints, err := LoadCheckpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(checkpoints); want != got {
+		t.Fatalf("checkpoints want %d got %d", want, got)
+	}
+	cp := checkpoints[0]
+	if want, got := q.ID, cp.QuestionID; want != got {
+		t.Errorf("questionID want %d got %d", want, got)
+	}
+
+	g, err = RestoreGame(cp, make(chan Message), qnaDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.setup()
+	if _, err := g.startRound(cp.Round); err != nil {
+		t.Fatal(err)
+	}
+
+	// the interrupted round continues without drawing a new question
+	if want, got := 5, g.totalRoundPlayed; want != got {
+		t.Errorf("totalRoundPlayed want %d got %d", want, got)
+	}
+	if want, got := 1, len(g.rank); want != got {
+		t.Fatalf("len(rank) want %d got %d", want, got)
+	}
+	if want, got := 20, g.rank[0].Score; want != got {
+		t.Errorf("score want %d got %d", want, got)
+	}
+
+	g.deleteCheckpoint()
+	if checkpoints, _ := LoadCheckpoints(); len(checkpoints) != 0 {
+		t.Errorf("checkpoint should be deleted, got %d", len(checkpoints))
+	}
+}
+
+func TestCheckpointResumeStartedAt(t *testing.T) {
+	oDB := repo.DefaultDB
+	defer func() { repo.DefaultDB = oDB }()
+	repo.DefaultDB = new(repo.MemoryDB)
+
+	qnaDB, err := qna.NewText("qna/questions.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// apa yang orang katakan sambil memegang pipi orang lain*33:gemes*20:lucu*12:sayang*...
+	q := testQuestion(t, "1")
+	players := map[model.PlayerID]model.Player{"1": {ID: "1", Name: "foo"}}
+	r, err := newRound(q, players)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the round was interrupted 40 seconds after it started, one second before it ends
+	now := time.Now()
+	r.startedAt, r.endAt = now.Add(-40*time.Second), now.Add(time.Second)
+	r.state = RoundStarted
+	r.answer(players["1"], "lucu", r.startedAt.Add(30*time.Second))
+
+	g := &Game{id: 1, ChanID: "1", chanName: "chan", players: players}
+	g.saveCheckpoint(1, r)
+	cp, found, err := LoadCheckpoint("1")
+	if err != nil || !found {
+		t.Fatalf("checkpoint not found, %v", err)
+	}
+
+	g, err = RestoreGame(cp, make(chan Message), qnaDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.setup()
+	g.scoring = SpeedBonus{Max: 20, Window: 60 * time.Second}
+	if _, err := g.startRound(cp.Round); err != nil {
+		t.Fatal(err)
+	}
+
+	// half of the window has passed: 20 + 20*30/60
+	if want, got := 30, g.rank[0].Score; want != got {
+		t.Errorf("score want %d got %d", want, got)
+	}
+	if want, got := 30*time.Second, g.roundRecap.Fastest.Elapsed; want != got {
+		t.Errorf("fastest want %s got %s", want, got)
+	}
+}
+
+func TestCheckpointFinalizing(t *testing.T) {
+	oDB := repo.DefaultDB
+	defer func() { repo.DefaultDB = oDB }()
+	repo.DefaultDB = new(repo.MemoryDB)
+
+	players := map[model.PlayerID]model.Player{"1": {ID: "1", Name: "foo"}}
+	g := &Game{id: 1, ChanID: "1", chanName: "chan", players: players, finalizing: true}
+	g.roundRecap = RoundRecap{MVP: model.PlayerScore{PlayerID: "1", Name: "foo", Score: 33}, Guesses: 4}
+	// checkpoint of the sudden death round
+	g.saveCheckpoint(RoundPerGame+1, nil)
+
+	checkpoints, err := LoadCheckpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := checkpoints[0]
+	if want, got := RoundPerGame, cp.Round; want != got {
+		t.Errorf("round want %d got %d", want, got)
+	}
+	g, err = RestoreGame(cp, make(chan Message), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.finalizing {
+		t.Errorf("restored game should be finalizing")
+	}
+	if want, got := 4, g.roundRecap.Guesses; want != got {
+		t.Errorf("recap guesses want %d got %d", want, got)
+	}
+
+	cp.Round = RoundPerGame + 1
+	if _, err := RestoreGame(cp, make(chan Message), nil); err == nil {
+		t.Errorf("round after the last round should be rejected")
+	}
+}
+
+func TestCheckpointFixedSequence(t *testing.T) {
+	oDB := repo.DefaultDB
+	defer func() { repo.DefaultDB = oDB }()
+	repo.DefaultDB = new(repo.MemoryDB)
+
+	g := &Game{id: 1, ChanID: "1", chanName: "chan"}
+	g.SetQuestionSequence(42, 3)
+	g.saveCheckpoint(2, nil)
+
+	cp, found, err := LoadCheckpoint("1")
+	if err != nil || !found {
+		t.Fatalf("checkpoint should be found, err %v", err)
+	}
+	g, err = RestoreGame(cp, make(chan Message), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, played, fixed := g.QuestionSequence()
+	if !fixed {
+		t.Fatalf("restored game should keep the fixed sequence")
+	}
+	if want, got := int64(42), seed; want != got {
+		t.Errorf("seed want %d got %d", want, got)
+	}
+	if want, got := 3, played; want != got {
+		t.Errorf("played want %d got %d", want, got)
+	}
+}
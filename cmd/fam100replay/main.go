@@ -0,0 +1,101 @@
+// fam100replay re-runs a game journal through the game engine and prints
+// the board and ranking after every event
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/repo"
+)
+
+var (
+	journalDir = "journal"
+	gameID     = int64(0)
+	kinds      = ""
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	flag.StringVar(&journalDir, "dir", "journal", "journal directory")
+	flag.Int64Var(&gameID, "game", 0, "game id to replay")
+	flag.StringVar(&kinds, "kinds", "", "comma separated journal kinds to print, empty prints all")
+	flag.Parse()
+
+	path := flag.Arg(0)
+	if path == "" {
+		if gameID == 0 {
+			fmt.Fprintln(os.Stderr, "usage: fam100replay [-dir journal] -game <gameID> | fam100replay <journal file>")
+			os.Exit(2)
+		}
+		path = fam100.JournalPath(journalDir, gameID)
+	}
+
+	entries, err := fam100.ReadJournal(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	show := make(map[fam100.JournalKind]bool)
+	for _, k := range strings.Split(kinds, ",") {
+		if k != "" {
+			show[fam100.JournalKind(k)] = true
+		}
+	}
+
+	// replay must not change the stored scores
+	repo.DefaultDB = new(repo.MemoryDB)
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	n := 0
+	err = fam100.Replay(entries, func(s fam100.ReplayStep) {
+		n++
+		if len(show) > 0 && !show[s.Entry.Kind] {
+			return
+		}
+		fmt.Fprintf(w, "#%d %s %s %s\n", n, s.Entry.At.Format("15:04:05.000"), s.Entry.Kind, s.Entry.Data)
+		if s.Board != nil {
+			printBoard(w, *s.Board)
+		}
+		printRank(w, s)
+		fmt.Fprintln(w)
+	})
+	if err != nil {
+		w.Flush()
+		log.Fatal(err)
+	}
+}
+
+func printBoard(w *bufio.Writer, msg fam100.QNAMessage) {
+	fmt.Fprintf(w, "  [round %d, id: %d] %s?\n", msg.Round, msg.QuestionID, msg.QuestionText)
+	for i, a := range msg.Answers {
+		switch {
+		case a.Answered:
+			fmt.Fprintf(w, "  %d. (%2d) %s ✓ %s\n", i+1, a.Score, a.Text, a.PlayerName)
+		case msg.ShowUnanswered:
+			fmt.Fprintf(w, "  %d. (%2d) %s\n", i+1, a.Score, a.Text)
+		case a.Hint != "":
+			fmt.Fprintf(w, "  %d. %s\n", i+1, a.Hint)
+		default:
+			fmt.Fprintf(w, "  %d. ______\n", i+1)
+		}
+	}
+	if msg.Strikes > 0 {
+		fmt.Fprintf(w, "  strikes: %d\n", msg.Strikes)
+	}
+}
+
+func printRank(w *bufio.Writer, s fam100.ReplayStep) {
+	for _, ts := range s.TeamRank {
+		fmt.Fprintf(w, "  team %d. (%2d) %s\n", ts.Position, ts.Score, ts.Team)
+	}
+	for _, ps := range s.Rank {
+		fmt.Fprintf(w, "  rank %d. (%2d) %s\n", ps.Position, ps.Score, ps.Name)
+	}
+}
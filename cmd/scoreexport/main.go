@@ -10,21 +10,21 @@ import (
 	"strings"
 	"time"
 
-	"github.com/garyburd/redigo/redis"
-	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
 )
 
 var (
 	outdir       = "scores"
-	reset        = false
 	overrideWeek = -1
+	timezone     = "Asia/Jakarta"
 )
 
 type scoreFile struct {
-	ChanID      string                 `json:"chanID"`
-	LastUpdated string                 `json:"lastUpdated"`
-	Total       fam100.Rank            `json:"total"`
-	Rank        map[string]fam100.Rank `json:"rank"`
+	ChanID      string                `json:"chanID"`
+	LastUpdated string                `json:"lastUpdated"`
+	Total       model.Rank            `json:"total"`
+	Rank        map[string]model.Rank `json:"rank"`
 }
 
 func (sf scoreFile) write() error {
@@ -43,82 +43,77 @@ func (sf scoreFile) write() error {
 	return nil
 }
 
+// weekOf returns a time in the ISO week of the current year
+func weekOf(week int) time.Time {
+	// january 4th is always in the first week
+	jan4 := time.Date(time.Now().In(repo.Location).Year(), 1, 4, 12, 0, 0, 0, repo.Location)
+	return jan4.AddDate(0, 0, (week-1)*7)
+}
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	flag.StringVar(&outdir, "outdir", "scores", "output directory results")
-	flag.BoolVar(&reset, "reset", false, "reset current week score")
 	flag.IntVar(&overrideWeek, "week", -1, "override week")
+	flag.StringVar(&timezone, "timezone", "Asia/Jakarta", "time zone of the leaderboard periods, must match the bot")
+	flag.Parse()
 
 	if outdir == "" {
 		log.Fatal("outdir cannot be empty")
 	}
-	flag.Parse()
-	year, week := time.Now().ISOWeek()
-	if overrideWeek > 0 {
-		week = overrideWeek
-	}
-	currentWeekKey := fmt.Sprintf("%d-%d", year, week)
-	lastUpdated := time.Now().Format(time.RFC3339)
-
-	// get all score from redis
-	conn, err := redis.Dial("tcp", ":6379")
+	loc, err := time.LoadLocation(timezone)
 	if err != nil {
 		log.Fatal(err)
 	}
+	repo.Location = loc
 
-	keys, err := redis.ByteSlices(conn.Do("keys", "fam100_chan_rank_*"))
-	if err != nil {
-		log.Fatal(err)
+	at := time.Now()
+	if overrideWeek > 0 {
+		at = weekOf(overrideWeek)
 	}
+	year, week := at.In(loc).ISOWeek()
+	weekKey := fmt.Sprintf("%d-%d", year, week)
+	lastUpdated := time.Now().Format(time.RFC3339)
 
-	if err := fam100.DefaultDB.Init(); err != nil {
+	if err := repo.DefaultDB.Init(); err != nil {
 		log.Fatal(err)
 	}
 	if err := os.MkdirAll(outdir, 0744); err != nil {
 		log.Fatal(err)
 	}
 
-	for _, key := range keys {
-		chanID := strings.TrimPrefix(string(key), "fam100_chan_rank_")
+	channels, err := repo.DefaultDB.Channels()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for chanID := range channels {
 		if chanID == "" {
-			log.Printf("WARNING empty key, %s skipping\n", key)
+			log.Printf("WARNING empty chanID, skipping\n")
 			continue
 		}
 
-		// read total
 		sf, err := readFile(chanID)
 		if err != nil {
 			log.Printf("ERROR, failed loading file, chanID %s", chanID)
 			continue
 		}
 
-		// subtract total from current week
-		total := sf.Total
-		currentWeek := sf.Rank[currentWeekKey]
-		total = total.Subtract(currentWeek)
-
-		// update total from new currentWeek data
-		currentWeek, err = fam100.DefaultDB.ChannelRanking(chanID, 0)
+		// weekly ranking is kept by the store, the file keeps weeks after the store retention
+		total, err := repo.DefaultDB.ChannelRanking(chanID, repo.PeriodAll, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		weekRank, err := repo.DefaultDB.ChannelRankingAt(chanID, repo.PeriodWeek, at, 0)
 		if err != nil {
 			log.Fatal(err)
 		}
-		total = total.Add(currentWeek)
 
-		// write total & current week
 		sf.ChanID = chanID
 		sf.Total = total
-		sf.Rank[currentWeekKey] = currentWeek
+		sf.Rank[weekKey] = weekRank
 		sf.LastUpdated = lastUpdated
 		if err := sf.write(); err != nil {
 			log.Fatal(err)
 		}
-
-		// reset current week data if reset flag is true
-		if reset {
-			if _, err := conn.Do("DEL", key); err != nil {
-				log.Panic(err)
-			}
-		}
 	}
 }
 
@@ -128,12 +123,15 @@ func readFile(chanID string) (scoreFile, error) {
 	file, err := os.Open(fileName)
 	if err != nil {
 		log.Printf("WARNING: chanID:%s,  %s, crating new file", chanID, err)
-		return scoreFile{Rank: make(map[string]fam100.Rank)}, nil
+		return scoreFile{Rank: make(map[string]model.Rank)}, nil
 	}
 
 	dec := json.NewDecoder(file)
 	var sf scoreFile
 	err = dec.Decode(&sf)
+	if sf.Rank == nil {
+		sf.Rank = make(map[string]model.Rank)
+	}
 
 	return sf, err
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/achievement"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// announceAwards announces new badges in the channel where they were earned
+func (b *fam100Bot) announceAwards(awards []achievement.Award) {
+	for _, a := range awards {
+		badgeAwardedCount.Inc(1)
+		log.Info("Badge awarded", zap.String("chanID", a.ChanID), zap.String("playerID", string(a.Player.ID)), zap.String("badge", a.Badge.ID))
+		text := fmt.Sprintf(fam100.T("🏅 <b>%s</b> mendapat badge <b>%s</b>: %s"), escape(a.Player.Name), escape(a.Badge.Name), escape(a.Badge.Description))
+		b.out <- bot.Message{Chat: bot.Chat{ID: a.ChanID}, Text: text, Format: bot.HTML, Retry: 3}
+	}
+}
+
+// cmdBadges handles "/badges", lists the badges of the player
+func (b *fam100Bot) cmdBadges(msg *bot.Message) bool {
+	defer cmdBadgesTimer.UpdateSince(time.Now())
+
+	if rateLimited("badges", msg.Chat.ID+msg.From.ID, cmdRateDelay) {
+		return true
+	}
+
+	commandBadgesCount.Inc(1)
+	chanID := msg.Chat.ID
+	badges, err := repo.DefaultDB.Badges(model.PlayerID(msg.From.ID))
+	if err != nil {
+		log.Error("getting badges failed", zap.String("chanID", chanID), zap.Error(err))
+		return true
+	}
+
+	var lines []string
+	earned := 0
+	for _, rule := range b.achievements.Rules {
+		mark := "▫️"
+		if _, ok := badges[rule.Badge.ID]; ok {
+			mark = "🏅"
+			earned++
+			delete(badges, rule.Badge.ID)
+		}
+		lines = append(lines, fmt.Sprintf("%s <b>%s</b> - %s\n", mark, escape(rule.Badge.Name), escape(rule.Badge.Description)))
+	}
+
+	// badges without a rule, such as season placements, newest first
+	others := make([]string, 0, len(badges))
+	for id := range badges {
+		others = append(others, id)
+	}
+	sort.Slice(others, func(i, j int) bool { return badges[others[i]].After(badges[others[j]]) })
+	for _, id := range others {
+		badge, ok := b.achievements.Badge(id)
+		if !ok {
+			badge = achievement.Badge{ID: id, Name: id}
+		}
+		line := fmt.Sprintf("🏆 <b>%s</b>\n", escape(badge.Name))
+		if badge.Description != "" {
+			line = fmt.Sprintf("🏆 <b>%s</b> - %s\n", escape(badge.Name), escape(badge.Description))
+		}
+		lines = append(lines, line)
+	}
+
+	text := fmt.Sprintf(fam100.T("<b>Badge %s</b> (%d/%d)\n"), escape(msg.From.FullName()), earned, len(b.achievements.Rules))
+	text += strings.Join(lines, "")
+	b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+
+	return true
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/fam100/tournament"
+)
+
+// serveAPI serves read only game data as JSON
+func serveAPI(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tournaments", apiTournaments)
+	mux.HandleFunc("/api/tournaments/", apiTournament)
+
+	log.Info("api listener", zap.String("addr", addr))
+	log.Error("api listener stopped", zap.Error(http.ListenAndServe(addr, mux)))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("failed to encode api response", zap.Error(err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// apiTournaments handles GET /api/tournaments
+func apiTournaments(w http.ResponseWriter, r *http.Request) {
+	tournaments, err := loadTournaments()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tournaments)
+}
+
+// apiTournament handles GET /api/tournaments/{id}, the tournament with its bracket and results
+func apiTournament(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/tournaments/")
+	t, err := loadTournament(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	br, _, err := tournamentBracket(t)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		*tournament.Tournament
+		Bracket *tournament.Bracket `json:"bracket"`
+	}{t, br})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// autoplayChan receives channel whose auto-play lobby is ready to start
+var autoplayChan = make(chan string, 10000)
+
+// autoplayEnabled returns whether the channel starts a new game after one is finished
+func autoplayEnabled(chanID string) bool {
+	v, err := repo.DefaultDB.ChannelConfig(chanID, "autoplay", "")
+	if err != nil || v == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(v)
+	return err == nil && enabled
+}
+
+// autoplayIdleTime returns how long the channel can be quiet before auto-play stops
+func autoplayIdleTime(chanID string) time.Duration {
+	v, err := repo.DefaultDB.ChannelConfig(chanID, "autoplayIdle", "")
+	if err != nil || v == "" {
+		return time.Duration(autoplayIdle) * time.Minute
+	}
+	minutes, err := strconv.Atoi(v)
+	if err != nil || minutes <= 0 {
+		return time.Duration(autoplayIdle) * time.Minute
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// autoplay opens the next game of a finished channel with players that answered
+// recently. It returns false if the channel does not continue playing
+func (b *fam100Bot) autoplay(prev *channel) bool {
+	chanID := prev.ID
+	if prev.stopped || !autoplayEnabled(chanID) {
+		return false
+	}
+
+	// answers are part of the game checkpoint, so auto-play continues after a restart
+	players := prev.game.Answerers(time.Now().Add(-autoplayIdleTime(chanID)))
+	if len(players) == 0 {
+		text := fam100.T("Auto-play berhenti, tidak ada yang bermain. Ketik /join untuk memulai lagi")
+		b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+		log.Info("Auto-play idle", zap.String("chanID", chanID))
+		return false
+	}
+
+	// the lease may have expired while the game was finishing
+	lease, owned := b.claim(chanID)
+	if !owned {
+		log.Warn("Auto-play lease lost", zap.String("chanID", chanID))
+		return false
+	}
+	gameIn := make(chan fam100.Message, gameInBufferSize)
+	game, err := fam100.NewGame(chanID, prev.Name, gameIn, b.qnaDB)
+	if err != nil {
+		log.Error("creating a game", zap.String("chanID", chanID))
+		b.release(chanID, lease)
+		return false
+	}
+	game.Fence = lease
+	b.subscribe(game)
+
+	ch := &channel{
+		ID:           chanID,
+		Name:         prev.Name,
+		game:         game,
+		lease:        lease,
+		quorumPlayer: make(map[string]bool),
+		players:      make(map[string]string),
+		teams:        make(map[string]string),
+	}
+	for _, p := range players {
+		id := string(p.ID)
+		ch.quorumPlayer[id] = true
+		ch.players[id] = p.Name
+		if team, ok := prev.teams[id]; ok {
+			ch.teams[id] = team
+		}
+		game.JoinTeam(p, prev.teams[id])
+	}
+	b.channels[chanID] = ch
+	ch.saveLobby()
+	autoplayCount.Inc(1)
+
+	if len(ch.quorumPlayer) >= minQuorum {
+		text := fmt.Sprintf(fam100.T("Auto-play: game berikutnya dimulai dalam %s dengan %d pemain. Ketik /stop untuk berhenti"), autoplayDelay, len(ch.quorumPlayer))
+		b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(autoplayDelay)}
+		ch.startAutoplayTimer(autoplayDelay)
+	} else {
+		text := fmt.Sprintf(fam100.T("Auto-play: %d pemain aktif, butuh %d orang lagi, ketik /join"), len(ch.quorumPlayer), minQuorum-len(ch.quorumPlayer))
+		b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(quorumWait)}
+		ch.startQuorumTimer(quorumWait, b.out)
+	}
+	log.Info("Auto-play lobby", zap.String("chanID", chanID), zap.Int("players", len(ch.quorumPlayer)))
+
+	return true
+}
+
+// startAutoplayTimer starts the game after wait unless it was canceled
+func (c *channel) startAutoplayTimer(wait time.Duration) {
+	var ctx context.Context
+	ctx, c.cancelTimer = context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+			autoplayChan <- c.ID
+		}
+	}()
+}
+
+// cmdStop stops auto-play of the channel, a lobby that is not started yet is canceled
+func (b *fam100Bot) cmdStop(msg *bot.Message) bool {
+	defer cmdStopTimer.UpdateSince(time.Now())
+
+	chanID := msg.Chat.ID
+	ch, ok := b.channels[chanID]
+	if !ok || ch.stopped || !autoplayEnabled(chanID) {
+		return true
+	}
+	if _, joined := ch.players[msg.From.ID]; !joined {
+		// only players can stop the game
+		return true
+	}
+
+	commandStopCount.Inc(1)
+	ch.stopped = true
+	if ch.game.State != fam100.Created {
+		text := fam100.T("Auto-play dihentikan, ini adalah game terakhir")
+		b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+		return true
+	}
+
+	if ch.cancelTimer != nil {
+		ch.cancelTimer()
+	}
+	if ch.cancelNotifyTimer != nil {
+		ch.cancelNotifyTimer()
+	}
+	delete(b.channels, chanID)
+	deleteLobby(chanID)
+	b.release(chanID, ch.lease)
+	text := fam100.T("Auto-play dihentikan")
+	b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+	log.Info("Auto-play stopped", zap.String("chanID", chanID), zap.String("by", msg.From.ID))
+
+	return true
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/qna"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// finishedChannel returns a channel whose game was answered by players at the given time
+func finishedChannel(t *testing.T, chanID string, questionDB qna.Provider, answeredAt map[model.PlayerID]time.Time) *channel {
+	cp := fam100.Checkpoint{GameID: 1, ChanID: chanID, ChanName: "autoplay", Round: fam100.RoundPerGame, LastAnswerAt: answeredAt}
+	for id := range answeredAt {
+		cp.Rank = append(cp.Rank, model.PlayerScore{PlayerID: id, Name: "Player " + string(id), Score: 10})
+	}
+	game, err := fam100.RestoreGame(cp, make(chan fam100.Message), questionDB)
+	if err != nil {
+		t.Fatalf("failed to restore game: %v", err)
+	}
+
+	return &channel{ID: chanID, Name: "autoplay", game: game, teams: map[string]string{"1": "merah"}}
+}
+
+func TestAutoplay(t *testing.T) {
+	repo.RedisPrefix = "test_fam100"
+	if err := repo.DefaultDB.Init(); err != nil {
+		t.Fatalf("failed to load database: %v", err)
+	}
+	if err := repo.DefaultDB.Reset(); err != nil {
+		t.Fatalf("failed to reset database: %v", err)
+	}
+	questionDB, err := qna.NewText("../qna/questions.txt")
+	if err != nil {
+		t.Fatalf("failed to load questions: %v", err)
+	}
+
+	oMinQuorum, oAutoplayDelay := minQuorum, autoplayDelay
+	defer func() { minQuorum, autoplayDelay = oMinQuorum, oAutoplayDelay }()
+	minQuorum, autoplayDelay = 2, 10*time.Millisecond
+
+	b := &fam100Bot{
+		name:     "fam100bot",
+		qnaDB:    questionDB,
+		out:      make(chan bot.Message, 10),
+		channels: make(map[string]*channel),
+		gameOut:  make(chan fam100.Event, 10),
+	}
+	chanID := "autoplay"
+	if err := repo.DefaultDB.SetChannelConfig(chanID, "autoplay", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("only recent answerers join the next game", func(t *testing.T) {
+		now := time.Now()
+		prev := finishedChannel(t, chanID, questionDB, map[model.PlayerID]time.Time{
+			"1": now,
+			"2": now.Add(-time.Minute),
+			"3": now.Add(-time.Hour),
+		})
+		if !b.autoplay(prev) {
+			t.Fatal("auto-play should continue")
+		}
+		ch := b.channels[chanID]
+		if want, got := 2, len(ch.players); want != got {
+			t.Fatalf("players want %d got %d", want, got)
+		}
+		if _, ok := ch.players["3"]; ok {
+			t.Errorf("idle player should not join")
+		}
+		if want, got := "merah", ch.teams["1"]; want != got {
+			t.Errorf("team want %s got %s", want, got)
+		}
+		select {
+		case id := <-autoplayChan:
+			if want, got := chanID, id; want != got {
+				t.Errorf("autoplay channel want %s got %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for the auto-play game to start")
+		}
+		deleteLobby(chanID)
+		delete(b.channels, chanID)
+	})
+
+	t.Run("stops without answers", func(t *testing.T) {
+		prev := finishedChannel(t, chanID, questionDB, map[model.PlayerID]time.Time{"1": time.Now().Add(-time.Hour)})
+		if b.autoplay(prev) {
+			t.Error("auto-play should stop when nobody answered recently")
+		}
+		if _, ok := b.channels[chanID]; ok {
+			t.Error("stopped auto-play should not open a lobby")
+		}
+	})
+
+	t.Run("stops when stopped", func(t *testing.T) {
+		prev := finishedChannel(t, chanID, questionDB, map[model.PlayerID]time.Time{"1": time.Now(), "2": time.Now()})
+		prev.stopped = true
+		if b.autoplay(prev) {
+			t.Error("auto-play should not continue after /stop")
+		}
+	})
+}
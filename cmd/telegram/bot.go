@@ -12,9 +12,12 @@ import (
 	"github.com/uber-go/zap"
 	"github.com/yulrizka/bot"
 	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/achievement"
 	"github.com/yulrizka/fam100/model"
 	"github.com/yulrizka/fam100/qna"
 	"github.com/yulrizka/fam100/repo"
+	"github.com/yulrizka/fam100/stats"
+	"github.com/yulrizka/fam100/webhook"
 )
 
 type fam100Bot struct {
@@ -24,13 +27,24 @@ type fam100Bot struct {
 	channels map[string]*channel
 	name     string
 
+	// instance is the id of this bot when channels are sharded, empty to disable
+	instance string
+
 	// channel to communicate with game
-	gameOut chan fam100.Message
+	gameOut chan fam100.Event
 	quit    chan struct{}
 
 	// question database
 	qnaDB qna.Provider
 
+	hooks      *webhook.Dispatcher
+	webhookOut chan fam100.Event
+
+	// record and achievements of players, evaluated from game events
+	records      *stats.Recorder
+	achievements *achievement.Engine
+	progressOut  chan fam100.Event
+
 	cl bot.Client
 }
 
@@ -43,11 +57,23 @@ func (b *fam100Bot) Init(ctx context.Context, out chan bot.Message, cl bot.Clien
 	b.in = make(chan interface{}, telegramInBufferSize)
 	b.out = out
 
-	b.gameOut = make(chan fam100.Message, gameOutBufferSize)
+	b.gameOut = make(chan fam100.Event, gameOutBufferSize)
 	b.channels = make(map[string]*channel)
+	b.hooks = webhook.NewDispatcher(webhookQueueSize, repoDeliveryLog{})
+	b.hooks.Start(ctx, webhookWorker)
+	b.webhookOut = make(chan fam100.Event, webhookQueueSize)
+	b.records = stats.NewRecorder(repo.DefaultDB)
+	b.achievements = achievement.New(repo.DefaultDB, defaultLocation())
+	b.progressOut = make(chan fam100.Event, gameOutBufferSize)
+	b.resume()
+	if b.sharded() {
+		go b.receiveForwarded(ctx)
+	}
 
 	go b.handleOutbox()
 	go b.handleInbox()
+	go b.handleProgress()
+	go b.handleWebhooks()
 
 	return nil
 }
@@ -62,6 +88,13 @@ func (b *fam100Bot) Handle(_ context.Context, rawMsg interface{}) (handled bool,
 
 // handleInbox handles incoming message from telegram
 func (b *fam100Bot) handleInbox() {
+	var leaseTick <-chan time.Time
+	if b.sharded() {
+		leaseTick = time.Tick(leaseTTL / 3)
+	}
+	scheduleTick := time.Tick(scheduleInterval)
+	lastSchedule := time.Now()
+
 	for {
 		select {
 		case <-b.quit:
@@ -101,26 +134,82 @@ func (b *fam100Bot) handleInbox() {
 							cmdHandler, cmdMetric = b.cmdChannels, mainHandleChannelsTimer
 						case strings.HasPrefix(msg.Text, "/broadcast"):
 							cmdHandler, cmdMetric = b.cmdBroadcast, mainHandleBrodcastTimer
+						case strings.HasPrefix(msg.Text, "/webhooks"):
+							cmdHandler, cmdMetric = b.cmdWebhooks, mainHandleWebhooksTimer
+						case strings.HasPrefix(msg.Text, "/cheaters"):
+							cmdHandler, cmdMetric = b.cmdCheaters, mainHandleCheatersTimer
+						case strings.HasPrefix(msg.Text, "/tournament"):
+							cmdHandler, cmdMetric = b.cmdTournament, mainHandleTournamentTimer
+						case strings.HasPrefix(msg.Text, "/season"):
+							cmdHandler, cmdMetric = b.cmdSeason, mainHandleSeasonTimer
 						}
+					}
 
-						if cmdHandler != nil {
-							if cmdHandler(msg) {
-								cmdMetric.UpdateSince(start)
-							}
+					// practice game against the clock
+					if cmdHandler == nil {
+						cmd, _ := parseCommand(msg.Text, b.name)
+						switch cmd {
+						case "/practice":
+							cmdHandler, cmdMetric = b.cmdPractice, mainHandlePracticeTimer
+						case "/score":
+							cmdHandler, cmdMetric = b.cmdPracticeScore, mainHandlePracticeScoreTimer
+						case "/categories":
+							cmdHandler, cmdMetric = b.cmdCategories, mainHandleCategoriesTimer
+						case "/skip":
+							cmdHandler, cmdMetric = b.cmdSkip, mainHandleSkipTimer
+						case "/daily":
+							cmdHandler, cmdMetric = b.cmdDaily, mainHandleDailyTimer
+						case "/badges":
+							cmdHandler, cmdMetric = b.cmdBadges, mainHandleBadgesTimer
+						case "/me":
+							cmdHandler, cmdMetric = b.cmdMe, mainHandleMeTimer
+						case "/rating":
+							cmdHandler, cmdMetric = b.cmdRating, mainHandleRatingTimer
+						}
+					}
+
+					if cmdHandler != nil {
+						if cmdHandler(msg) {
+							cmdMetric.UpdateSince(start)
 						}
+					} else {
+						b.handlePracticeAnswer(msg)
 					}
 					mainHandlePrivateChatTimer.UpdateSince(start)
 					mainHandleMessageTimer.UpdateSince(start)
 					continue
 				}
 
+				// channel is owned by another instance
+				if b.forward(msg) {
+					mainHandleMessageTimer.UpdateSince(start)
+					continue
+				}
+
 				// Handle public commands
-				switch msg.Text {
-				case "/join", "/join@" + b.name:
+				cmd, _ := parseCommand(msg.Text, b.name)
+				switch cmd {
+				case "/join":
 					cmdHandler, cmdMetric = b.cmdJoin, mainHandleJoinTimer
-				case "/score", "/score@" + b.name:
+				case "/score":
 					cmdHandler, cmdMetric = b.cmdScore, mainHandleScoreTimer
-				case "/help", "/help@" + b.name:
+				case "/skip":
+					cmdHandler, cmdMetric = b.cmdSkip, mainHandleSkipTimer
+				case "/stop":
+					cmdHandler, cmdMetric = b.cmdStop, mainHandleStopTimer
+				case "/schedule":
+					cmdHandler, cmdMetric = b.cmdSchedule, mainHandleScheduleTimer
+				case "/tournament":
+					cmdHandler, cmdMetric = b.cmdBracket, mainHandleBracketTimer
+				case "/daily":
+					cmdHandler, cmdMetric = b.cmdDaily, mainHandleDailyTimer
+				case "/badges":
+					cmdHandler, cmdMetric = b.cmdBadges, mainHandleBadgesTimer
+				case "/me":
+					cmdHandler, cmdMetric = b.cmdMe, mainHandleMeTimer
+				case "/rating":
+					cmdHandler, cmdMetric = b.cmdRating, mainHandleRatingTimer
+				case "/help":
 					// disabling to reduce outgoing message
 					// cmdHandler, cmdMetric = b.cmdHelp, mainHandleScoreTimer
 					continue
@@ -144,7 +233,7 @@ func (b *fam100Bot) handleInbox() {
 					mainHandleMessageTimer.UpdateSince(start)
 					continue
 				}
-				if len(ch.quorumPlayer) < minQuorum {
+				if len(ch.quorumPlayer) < ch.quorum() {
 					// ignore message if no game started or it's not quorum yet
 					mainHandleMinQuorumTimer.UpdateSince(start)
 					mainHandleMessageTimer.UpdateSince(start)
@@ -168,13 +257,44 @@ func (b *fam100Bot) handleInbox() {
 
 		case chanID := <-timeoutChan:
 			// chan failed to get quorum
+			if ch, ok := b.channels[chanID]; ok {
+				b.finishMatch(ch)
+				b.release(chanID, ch.lease)
+			}
 			delete(b.channels, chanID)
-			text := fmt.Sprintf(fam100.T("Permainan dibatalkan, jumlah pemain tidak cukup  😞"))
+			deleteLobby(chanID)
+			text := fam100.T("Permainan dibatalkan, jumlah pemain tidak cukup  😞")
 			b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.Markdown, DiscardAfter: time.Now().Add(5 * time.Second)}
 			log.Info("Quorum timeout", zap.String("chanID", chanID))
 
 		case chanID := <-finishedChan:
+			ch, ok := b.channels[chanID]
 			delete(b.channels, chanID)
+			if !ok {
+				continue
+			}
+			b.finishMatch(ch)
+			if !b.autoplay(ch) {
+				b.release(chanID, ch.lease)
+			}
+
+		case chanID := <-autoplayChan:
+			ch, ok := b.channels[chanID]
+			if !ok || ch.game.State != fam100.Created {
+				continue
+			}
+			deleteLobby(chanID)
+			ch.game.Start()
+
+		case <-leaseTick:
+			b.renewLeases()
+			// take over channels of instances that died
+			b.resume()
+
+		case now := <-scheduleTick:
+			b.runSchedules(lastSchedule, now)
+			lastSchedule = now
+			b.runTournaments(now)
 		}
 	}
 }
@@ -218,16 +338,32 @@ func (b *fam100Bot) handleOutbox() {
 						text = fmt.Sprintf(fam100.T("Game (id: %d) dimulai\n<b>siapapun boleh menjawab tanpa</b> /join\n"), msg.GameID)
 					}
 					roundStartedCount.Inc(1)
-					text += fmt.Sprintf(fam100.T("Ronde %d dari %d"), msg.Round, fam100.RoundPerGame)
+					if msg.Round > fam100.RoundPerGame {
+						text += fam100.T("<b>Sudden death</b>")
+					} else {
+						text += fmt.Sprintf(fam100.T("Ronde %d dari %d"), msg.Round, fam100.RoundPerGame)
+					}
+					if msg.Team != "" {
+						text += fmt.Sprintf(fam100.T("\nGiliran tim <b>%s</b>"), escape(msg.Team))
+					}
 					text += "\n\n" + formatRoundText(msg.RoundText)
 					b.out <- bot.Message{Chat: bot.Chat{ID: msg.ChanID}, Text: text, Format: bot.HTML, Retry: 3}
 
+				case fam100.RoundSteal:
+					text := fmt.Sprintf(fam100.T("Waktu habis! Tim <b>%s</b> boleh mencuri dengan satu tebakan, sisa waktu %s"), escape(msg.Team), fam100.StealDuration)
+					b.out <- bot.Message{Chat: bot.Chat{ID: msg.ChanID}, Text: text, Format: bot.HTML, Retry: 3}
+
 				case fam100.RoundFinished:
 					roundFinishedCount.Inc(1)
 
 				case fam100.RoundTimeout:
 					roundTimeoutCount.Inc(1)
 
+				case fam100.RoundSkipped:
+					roundSkippedCount.Inc(1)
+					text := fam100.T("Soal dilewati, ganti soal baru")
+					b.out <- bot.Message{Chat: bot.Chat{ID: msg.ChanID}, Text: text, Format: bot.HTML, Retry: 3}
+
 				case fam100.Finished:
 					gameFinishedCount.Inc(1)
 					finishedChan <- msg.ChanID
@@ -245,13 +381,55 @@ func (b *fam100Bot) handleOutbox() {
 				}
 				b.out <- outMsg
 
+			case fam100.SuddenDeathMessage:
+				names := make([]string, 0, len(msg.Players))
+				for _, ps := range msg.Players {
+					names = append(names, ps.Name)
+				}
+				text := fmt.Sprintf(fam100.T("Skor sama! <b>Sudden death</b> antara %s, jawaban benar pertama menang"), escape(strings.Join(names, ", ")))
+				if msg.Finished {
+					text = fam100.T("Tidak ada pemenang sudden death, pemenang ditentukan dari jawaban benar tercepat")
+					if msg.Winner.PlayerID != "" {
+						text = fmt.Sprintf(fam100.T("<b>%s</b> memenangkan sudden death!"), escape(msg.Winner.Name))
+					}
+				}
+				b.out <- bot.Message{Chat: bot.Chat{ID: msg.ChanID}, Text: text, Format: bot.HTML, Retry: 3}
+
+			case fam100.SkipVoteMessage:
+				text := fmt.Sprintf(fam100.T("<b>%s</b> ingin melewati soal ini (%d/%d) /skip"), escape(msg.Player.Name), msg.Votes, msg.Needed)
+				b.out <- bot.Message{Chat: bot.Chat{ID: msg.ChanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(5 * time.Second)}
+
+			case fam100.StrikeMessage:
+				var text string
+				if msg.BoardStrikeLimit > 0 {
+					text = fmt.Sprintf("%s (%d/%d)", strings.Repeat("❌", msg.BoardStrikes), msg.BoardStrikes, msg.BoardStrikeLimit)
+				}
+				if msg.Locked {
+					text += fmt.Sprintf(fam100.T("\n<b>%s</b> salah %d kali, tunggu ronde berikutnya"), escape(msg.Player.Name), msg.Strikes)
+				}
+				if text == "" {
+					sent = false
+					continue
+				}
+				b.out <- bot.Message{Chat: bot.Chat{ID: msg.ChanID}, Text: strings.TrimSpace(text), Format: bot.HTML, DiscardAfter: time.Now().Add(5 * time.Second)}
+
+			case fam100.StealMessage:
+				text := fmt.Sprintf(fam100.T("Tim <b>%s</b> gagal mencuri"), escape(msg.Team))
+				if msg.Success {
+					text = fmt.Sprintf(fam100.T("Tim <b>%s</b> berhasil mencuri! (%s)"), escape(msg.Team), escape(msg.Player.Name))
+				}
+				b.out <- bot.Message{Chat: bot.Chat{ID: msg.ChanID}, Text: text, Format: bot.HTML, Retry: 3}
+
 			case fam100.RankMessage:
-				text := formatRankText(msg.Rank)
+				text := formatRankText(msg.Rank, model.StandardRank)
+				if len(msg.TeamRank) > 0 {
+					text = formatTeamRankText(msg.TeamRank) + text
+				}
 				if msg.Final {
 					text = fam100.T("<b>Final score</b>:") + text
 
 					// show leader board, TOP 3 + current game players
-					rank, err := repo.DefaultDB.ChannelRanking(msg.ChanID, 3)
+					rank, err := repo.DefaultDB.ChannelRanking(msg.ChanID, repo.PeriodAll, 3)
 					if err != nil {
 						log.Error("getting channel ranking failed", zap.String("chanID", msg.ChanID), zap.Error(err))
 						continue
@@ -261,20 +439,27 @@ func (b *fam100Bot) handleOutbox() {
 						lookup[v.PlayerID] = true
 					}
 					for _, v := range msg.Rank {
-						if !lookup[v.PlayerID] {
-							playerScore, err := repo.DefaultDB.PlayerChannelScore(msg.ChanID, v.PlayerID)
-							if err != nil {
-								continue
+						if lookup[v.PlayerID] {
+							continue
+						}
+						// only the player, with the position in the channel ranking
+						around, err := repo.DefaultDB.ChannelRankingAround(msg.ChanID, repo.PeriodAll, v.PlayerID, 0)
+						if err != nil {
+							log.Error("getting player ranking failed", zap.String("chanID", msg.ChanID), zap.Error(err))
+							continue
+						}
+						for _, ps := range around {
+							if !lookup[ps.PlayerID] {
+								lookup[ps.PlayerID] = true
+								rank = append(rank, ps)
 							}
-
-							rank = append(rank, playerScore)
 						}
 					}
 					sort.Sort(rank)
-					text += "\n<b>Total Score</b>" + formatRankText(rank)
+					text += "\n<b>Total Score</b>" + formatRankText(rank, repo.DefaultDB.ChannelRankMode(msg.ChanID))
 
 					text += fmt.Sprintf("\nFull Score <a href=\"http://labs.yulrizka.com/fam100/scores.html?c=%s\">Lihat disini</a>\n", msg.ChanID)
-					text += fmt.Sprintf(fam100.T("\nGame selesai!"))
+					text += fam100.T("\nGame selesai!")
 					motd, _ := messageOfTheDay(msg.ChanID)
 					if motd != "" {
 						text = fmt.Sprintf("%s\n\n%s", text, motd)
@@ -282,6 +467,7 @@ func (b *fam100Bot) handleOutbox() {
 				} else {
 					text = fam100.T("Score sementara:") + text
 				}
+				text = formatRecapText(msg.Recap) + formatBreakdownText(msg.Breakdown) + text
 				b.out <- bot.Message{Chat: bot.Chat{ID: msg.ChanID}, Text: text, Format: bot.HTML, Retry: 3}
 
 			case fam100.TickMessage:
@@ -304,19 +490,33 @@ func (b *fam100Bot) handleOutbox() {
 // channel represents channels chat rooms
 type channel struct {
 	ID                string
+	Name              string
+	lease             int64 // fencing token of the channel lease, 0 if not sharded
+	stopped           bool  // auto-play was stopped
+	practice          bool  // played alone in a private chat
 	game              *fam100.Game
 	quorumPlayer      map[string]bool
 	players           map[string]string
+	teams             map[string]string
 	startedAt         time.Time
+	wait              time.Duration // time to wait for quorum, 0 is quorumWait
 	cancelTimer       context.CancelFunc
 	cancelNotifyTimer context.CancelFunc
 }
 
+// quorumWait returns how long the channel waits for quorum
+func (c *channel) quorumWait() time.Duration {
+	if c.wait > 0 {
+		return c.wait
+	}
+	return quorumWait
+}
+
 func (c *channel) startQuorumTimer(wait time.Duration, out chan bot.Message) {
 	var ctx context.Context
 	ctx, c.cancelTimer = context.WithCancel(context.Background())
 	go func() {
-		endAt := time.Now().Add(quorumWait)
+		endAt := time.Now().Add(wait)
 		notify := []int64{30}
 
 		for {
@@ -339,7 +539,7 @@ func (c *channel) startQuorumTimer(wait time.Duration, out chan bot.Message) {
 	}()
 }
 
-//TODO: refactor into simpler function with game context
+// TODO: refactor into simpler function with game context
 func (c *channel) startQuorumNotifyTimer(wait time.Duration, out chan bot.Message) {
 	var ctx context.Context
 	ctx, c.cancelNotifyTimer = context.WithCancel(context.Background())
@@ -349,7 +549,10 @@ func (c *channel) startQuorumNotifyTimer(wait time.Duration, out chan bot.Messag
 			return
 		case <-time.After(wait):
 			players := make([]string, 0, len(c.players))
-			for _, p := range c.players {
+			for id, p := range c.players {
+				if team := c.teams[id]; team != "" {
+					p = fmt.Sprintf("%s (%s)", p, team)
+				}
 				players = append(players, p)
 			}
 
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
+)
+
+const lobbyCheckpointKind = "lobby"
+
+// lobbyCheckpoint is the state of a channel that is waiting for quorum
+type lobbyCheckpoint struct {
+	ChanID   string            `json:"chanID"`
+	ChanName string            `json:"chanName"`
+	GameID   int64             `json:"gameID"`
+	Players  map[string]string `json:"players"`
+	Teams    map[string]string `json:"teams"`
+	Daily    string            `json:"daily,omitempty"`
+	Wait     time.Duration     `json:"wait,omitempty"`
+	SavedAt  time.Time         `json:"savedAt"`
+
+	// fixed question sequence such as of a tournament match
+	FixedSequence bool  `json:"fixedSequence,omitempty"`
+	Seed          int64 `json:"seed,omitempty"`
+	Played        int   `json:"played,omitempty"`
+}
+
+func (c *channel) saveLobby() {
+	lc := lobbyCheckpoint{
+		ChanID:   c.ID,
+		ChanName: c.Name,
+		GameID:   c.game.ID(),
+		Players:  c.players,
+		Teams:    c.teams,
+		Daily:    c.game.Daily,
+		Wait:     c.wait,
+		SavedAt:  time.Now(),
+	}
+	lc.Seed, lc.Played, lc.FixedSequence = c.game.QuestionSequence()
+	data, err := json.Marshal(lc)
+	if err != nil {
+		log.Error("failed to encode lobby checkpoint", zap.String("chanID", c.ID), zap.Error(err))
+		return
+	}
+	if err := repo.DefaultDB.SaveCheckpoint(lobbyCheckpointKind, c.ID, data); err != nil {
+		log.Error("failed to save lobby checkpoint", zap.String("chanID", c.ID), zap.Error(err))
+	}
+}
+
+func deleteLobby(chanID string) {
+	if err := repo.DefaultDB.DeleteCheckpoint(lobbyCheckpointKind, chanID); err != nil {
+		log.Error("failed to delete lobby checkpoint", zap.String("chanID", chanID), zap.Error(err))
+	}
+}
+
+// resume restores games and lobbies that were interrupted by a restart or whose
+// instance died. A checkpoint is only loaded once the lease of its channel is
+// acquired, checkpoint older than resumeMaxAge are canceled
+func (b *fam100Bot) resume() {
+	for _, kind := range []string{fam100.CheckpointKind, lobbyCheckpointKind} {
+		channels, err := repo.DefaultDB.CheckpointChannels(kind)
+		if err != nil {
+			log.Error("failed to list checkpoints", zap.String("kind", kind), zap.Error(err))
+			continue
+		}
+		for _, chanID := range channels {
+			if _, exists := b.channels[chanID]; exists {
+				continue
+			}
+			lease, owned := b.claim(chanID)
+			if !owned {
+				// still played by another instance
+				continue
+			}
+			resumed := false
+			if kind == fam100.CheckpointKind {
+				resumed = b.resumeGame(chanID, lease)
+			} else {
+				resumed = b.resumeLobby(chanID, lease)
+			}
+			if !resumed {
+				b.release(chanID, lease)
+			}
+		}
+	}
+}
+
+// resumeGame restores the game of a channel from its checkpoint
+func (b *fam100Bot) resumeGame(chanID string, lease int64) bool {
+	cp, found, err := fam100.LoadCheckpoint(chanID)
+	if err != nil {
+		log.Error("failed to load game checkpoint", zap.String("chanID", chanID), zap.Error(err))
+	}
+	if !found {
+		return false
+	}
+	if !b.resumable(cp.ChanID, cp.SavedAt) {
+		if err := fam100.DeleteCheckpoint(cp.ChanID); err != nil {
+			log.Error("failed to delete game checkpoint", zap.String("chanID", cp.ChanID), zap.Error(err))
+		}
+		return false
+	}
+
+	gameIn := make(chan fam100.Message, gameInBufferSize)
+	game, err := fam100.RestoreGame(cp, gameIn, b.qnaDB)
+	if err != nil {
+		log.Error("failed to restore game", zap.String("chanID", cp.ChanID), zap.Error(err))
+		return false
+	}
+	game.Fence = lease
+	b.subscribe(game)
+	ch := &channel{
+		ID:           cp.ChanID,
+		Name:         cp.ChanName,
+		game:         game,
+		lease:        lease,
+		quorumPlayer: make(map[string]bool),
+		players:      make(map[string]string),
+		teams:        make(map[string]string),
+	}
+	for id, p := range cp.Players {
+		ch.quorumPlayer[string(id)] = true
+		ch.players[string(id)] = p.Name
+		if team, ok := cp.Teams[id]; ok {
+			ch.teams[string(id)] = team
+		}
+	}
+	b.channels[cp.ChanID] = ch
+
+	text := fmt.Sprintf(fam100.T("Bot dijalankan ulang, game (id: %d) dilanjutkan dari ronde %d"), cp.GameID, cp.Round)
+	b.out <- bot.Message{Chat: bot.Chat{ID: cp.ChanID}, Text: text, Format: bot.HTML, Retry: 3}
+	game.Resume()
+	gameResumedCount.Inc(1)
+	log.Info("Game resumed", zap.String("chanID", cp.ChanID), zap.Int64("gameID", cp.GameID), zap.Int("round", cp.Round))
+
+	return true
+}
+
+// resumeLobby restores the lobby of a channel from its checkpoint
+func (b *fam100Bot) resumeLobby(chanID string, lease int64) bool {
+	data, err := repo.DefaultDB.Checkpoint(lobbyCheckpointKind, chanID)
+	if err != nil {
+		log.Error("failed to load lobby checkpoint", zap.String("chanID", chanID), zap.Error(err))
+	}
+	if data == nil {
+		return false
+	}
+	var lc lobbyCheckpoint
+	if err := json.Unmarshal(data, &lc); err != nil {
+		log.Error("invalid lobby checkpoint", zap.String("chanID", chanID), zap.Error(err))
+		deleteLobby(chanID)
+		return false
+	}
+	if !b.resumable(chanID, lc.SavedAt) {
+		deleteLobby(chanID)
+		return false
+	}
+
+	// keep the id, a tournament match is reported by the id of its game
+	gameIn := make(chan fam100.Message, gameInBufferSize)
+	var game *fam100.Game
+	if lc.GameID != 0 {
+		game, err = fam100.NewGameWithID(lc.GameID, chanID, lc.ChanName, gameIn, b.qnaDB)
+	} else {
+		game, err = fam100.NewGame(chanID, lc.ChanName, gameIn, b.qnaDB)
+	}
+	if err != nil {
+		log.Error("creating a game", zap.String("chanID", chanID))
+		return false
+	}
+	game.Fence = lease
+	if lc.Daily != "" {
+		game.SetDaily(lc.Daily)
+	}
+	if lc.FixedSequence {
+		game.SetQuestionSequence(lc.Seed, lc.Played)
+	}
+	b.subscribe(game)
+	ch := &channel{
+		ID:           chanID,
+		Name:         lc.ChanName,
+		game:         game,
+		lease:        lease,
+		wait:         lc.Wait,
+		quorumPlayer: make(map[string]bool),
+		players:      lc.Players,
+		teams:        lc.Teams,
+	}
+	if ch.players == nil {
+		ch.players = make(map[string]string)
+	}
+	if ch.teams == nil {
+		ch.teams = make(map[string]string)
+	}
+	for id, name := range ch.players {
+		ch.quorumPlayer[id] = true
+		game.JoinTeam(model.Player{ID: model.PlayerID(id), Name: name}, ch.teams[id])
+	}
+	b.channels[chanID] = ch
+
+	text := fmt.Sprintf(fam100.T("Bot dijalankan ulang, %d pemain sudah /join, butuh %d orang lagi"), len(ch.quorumPlayer), minQuorum-len(ch.quorumPlayer))
+	b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, Retry: 3}
+	ch.startQuorumTimer(ch.quorumWait(), b.out)
+	gameResumedCount.Inc(1)
+	log.Info("Lobby resumed", zap.String("chanID", chanID), zap.Int("players", len(ch.quorumPlayer)))
+
+	return true
+}
+
+// resumable returns whether the checkpoint saved at savedAt can be resumed,
+// otherwise the channel is notified that the game was canceled
+func (b *fam100Bot) resumable(chanID string, savedAt time.Time) bool {
+	if resumeMaxAge > 0 && time.Since(savedAt) <= time.Duration(resumeMaxAge)*time.Second {
+		return true
+	}
+
+	text := fam100.T("Bot dijalankan ulang, permainan sebelumnya dibatalkan. Ketik /join untuk memulai lagi")
+	b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+	gameCanceledCount.Inc(1)
+	log.Info("Checkpoint expired", zap.String("chanID", chanID), zap.String("savedAt", savedAt.String()))
+
+	return false
+}
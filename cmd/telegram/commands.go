@@ -5,6 +5,8 @@ import (
 	"bytes"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,7 +22,7 @@ var cmdRateDelay = 30 * time.Second
 
 var lastCmdRequest = make(map[string]time.Time)
 
-// handleJoin handles "/join". Create game and start it if quorum
+// handleJoin handles "/join [team]". Create game and start it if quorum
 func (b *fam100Bot) cmdJoin(msg *bot.Message) bool {
 	defer cmdJoinTimer.UpdateSince(time.Now())
 
@@ -31,21 +33,38 @@ func (b *fam100Bot) cmdJoin(msg *bot.Message) bool {
 	commandJoinCount.Inc(1)
 	chanID := msg.Chat.ID
 	chanName := msg.Chat.Title
+	player := model.Player{ID: model.PlayerID(msg.From.ID), Name: msg.From.FullName()}
+	_, args := parseCommand(msg.Text, b.name)
+	team := fam100.NormalizeTeam(strings.Join(args, " "))
+
 	ch, ok := b.channels[chanID]
 	if !ok {
+		lease, owned := b.claim(chanID)
+		if !owned {
+			// another instance just created the game
+			b.forward(msg)
+			return true
+		}
 		playerJoinedCount.Inc(1)
 		// create a new game
 		quorumPlayer := map[string]bool{msg.From.ID: true}
 		players := map[string]string{msg.From.ID: msg.From.FullName()}
+		teams := make(map[string]string)
+		if team != "" {
+			teams[msg.From.ID] = team
+		}
 
 		gameIn := make(chan fam100.Message, gameInBufferSize)
-		game, err := fam100.NewGame(chanID, chanName, gameIn, b.gameOut, b.qnaDB)
+		game, err := fam100.NewGame(chanID, chanName, gameIn, b.qnaDB)
 		if err != nil {
 			log.Error("creating a game", zap.String("chanID", chanID))
 			return true
 		}
+		game.JoinTeam(player, team)
+		game.Fence = lease
+		b.subscribe(game)
 
-		ch := &channel{ID: chanID, game: game, quorumPlayer: quorumPlayer, players: players}
+		ch := &channel{ID: chanID, Name: chanName, game: game, lease: lease, quorumPlayer: quorumPlayer, players: players, teams: teams}
 		b.channels[chanID] = ch
 
 		// quorum achieved, start the game
@@ -53,6 +72,7 @@ func (b *fam100Bot) cmdJoin(msg *bot.Message) bool {
 			ch.game.Start()
 			return true
 		}
+		ch.saveLobby()
 		ch.startQuorumTimer(quorumWait, b.out)
 		ch.startQuorumNotifyTimer(5*time.Second, b.out)
 		log.Info("User joined", zap.String("playerID", msg.From.ID), zap.String("chanID", chanID))
@@ -62,21 +82,31 @@ func (b *fam100Bot) cmdJoin(msg *bot.Message) bool {
 	if ch.game.State != fam100.Created || ch.quorumPlayer[msg.From.ID] {
 		return true
 	}
+	if ch.game.Daily != "" && b.dailyCompleted(msg, ch.game.Daily) {
+		return true
+	}
 
 	// new player joined
 	playerJoinedCount.Inc(1)
 	ch.cancelTimer()
 	ch.quorumPlayer[msg.From.ID] = true
 	ch.players[msg.From.ID] = msg.From.FullName()
+	if team != "" {
+		ch.teams[msg.From.ID] = team
+	}
+	ch.game.JoinTeam(player, team)
 
-	if len(ch.quorumPlayer) == minQuorum {
+	// auto-play lobby may already have the quorum
+	if len(ch.quorumPlayer) >= minQuorum {
 		if ch.cancelNotifyTimer != nil {
 			ch.cancelNotifyTimer()
 		}
+		deleteLobby(chanID)
 		ch.game.Start()
 		return true
 	}
-	ch.startQuorumTimer(quorumWait, b.out)
+	ch.saveLobby()
+	ch.startQuorumTimer(ch.quorumWait(), b.out)
 	if ch.cancelNotifyTimer == nil {
 		ch.startQuorumNotifyTimer(5*time.Second, b.out)
 	}
@@ -101,7 +131,54 @@ func (b *fam100Bot) cmdHelp(msg *bot.Message) bool {
 	return true
 }
 
-// handleJoin handles "/score" show top score for current channel
+// scorePageSize is the number of players in a page of "/score"
+var scorePageSize = 20
+
+// scoreAroundSize is the number of players above and below the caller in "/score me"
+var scoreAroundSize = 5
+
+// scoreQuery is the arguments of "/score"
+type scoreQuery struct {
+	period repo.Period
+	page   int
+	me     bool
+	season int // archived season, 0 for the running season
+}
+
+// parseScoreArgs parses "[day|week|month|all] [page N|me]" or "season N"
+func parseScoreArgs(args []string) (q scoreQuery, err error) {
+	q = scoreQuery{period: repo.PeriodAll, page: 1}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "me":
+			q.me = true
+		case "page":
+			if i+1 >= len(args) {
+				return q, fmt.Errorf("missing page number")
+			}
+			i++
+			if q.page, err = strconv.Atoi(args[i]); err != nil || q.page < 1 {
+				return q, fmt.Errorf("invalid page %q", args[i])
+			}
+		case "season":
+			if i+1 >= len(args) {
+				return q, fmt.Errorf("missing season number")
+			}
+			i++
+			if q.season, err = strconv.Atoi(args[i]); err != nil || q.season < 1 {
+				return q, fmt.Errorf("invalid season %q", args[i])
+			}
+		default:
+			if q.period, err = repo.ParsePeriod(args[i]); err != nil {
+				return q, err
+			}
+		}
+	}
+
+	return q, nil
+}
+
+// cmdScore handles "/score [day|week|month|all] [page N|me]" and "/score season N" show top score for current channel
 func (b *fam100Bot) cmdScore(msg *bot.Message) bool {
 	defer cmdScoreTimer.UpdateSince(time.Now())
 
@@ -109,25 +186,98 @@ func (b *fam100Bot) cmdScore(msg *bot.Message) bool {
 		return true
 	}
 
-	if rateLimited("score", msg.Chat.ID, cmdRateDelay) {
+	_, args := parseCommand(msg.Text, b.name)
+	q, err := parseScoreArgs(args)
+	if err != nil {
+		text := fam100.T("Gunakan <code>/score week</code>, <code>/score month</code>, <code>/score all</code>, <code>/score page 2</code>, <code>/score me</code> atau <code>/score season 1</code>")
+		b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(10 * time.Second)}
+		return true
+	}
+
+	// the arguments are not part of the limit, every page is a ranking query
+	limitKey := msg.Chat.ID
+	if msg.Chat.Type == bot.Private {
+		limitKey += msg.From.ID
+	}
+	if rateLimited("score", limitKey, cmdRateDelay) {
 		return true
 	}
 
 	commandScoreCount.Inc(1)
 	chanID := msg.Chat.ID
-	rank, err := repo.DefaultDB.ChannelRanking(chanID, 20)
+	title := periodTitle(q.period)
+	var rank model.Rank
+	switch {
+	case q.season > 0:
+		rank, err = repo.DefaultDB.SeasonRanking(chanID, q.season, scorePageSize)
+		title = fmt.Sprintf(fam100.T("Top Score Season %d"), q.season)
+	case q.me:
+		rank, err = repo.DefaultDB.ChannelRankingAround(chanID, q.period, model.PlayerID(msg.From.ID), scoreAroundSize)
+		title += " - " + msg.From.FullName()
+	default:
+		if q.period == repo.PeriodAll {
+			if season, err := repo.DefaultDB.Season(); err == nil && season > 0 {
+				title = fmt.Sprintf(fam100.T("Top Score Season %d"), season)
+			}
+		}
+		rank, err = repo.DefaultDB.ChannelRankingPage(chanID, q.period, (q.page-1)*scorePageSize, scorePageSize)
+		if q.page > 1 {
+			title += fmt.Sprintf(fam100.T(" (halaman %d)"), q.page)
+		}
+	}
 	if err != nil {
-		log.Error("getting channel ranking failed", zap.String("chanID", chanID), zap.Error(err))
+		log.Error("getting channel ranking failed", zap.String("chanID", chanID), zap.String("period", string(q.period)), zap.Error(err))
 		return true
 	}
 
-	text := "<b>Top Score:</b>\n" + formatRankText(rank)
+	text := fmt.Sprintf("<b>%s:</b>\n", escape(title)) + formatRankText(rank, repo.DefaultDB.ChannelRankMode(chanID))
+	if len(rank) == scorePageSize && !q.me && q.season == 0 {
+		next := fmt.Sprintf("/score page %d", q.page+1)
+		if q.period != repo.PeriodAll {
+			next = fmt.Sprintf("/score %s page %d", q.period, q.page+1)
+		}
+		text += fmt.Sprintf(fam100.T("\nHalaman berikutnya: <code>%s</code>"), next)
+	}
 	text += fmt.Sprintf("\n<a href=\"http://labs.yulrizka.com/fam100/scores.html?c=%s\">Full Score</a>", chanID)
 	b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(20 * time.Second)}
 
 	return true
 }
 
+// periodTitle is the title of the leaderboard of the period
+func periodTitle(period repo.Period) string {
+	switch period {
+	case repo.PeriodDay:
+		return fam100.T("Top Score hari ini")
+	case repo.PeriodWeek:
+		return fam100.T("Top Score minggu ini")
+	case repo.PeriodMonth:
+		return fam100.T("Top Score bulan ini")
+	}
+
+	return "Top Score"
+}
+
+// cmdSkip handles "/skip" vote to skip the current question
+func (b *fam100Bot) cmdSkip(msg *bot.Message) bool {
+	defer cmdSkipTimer.UpdateSince(time.Now())
+
+	// the game state belongs to the game goroutine, the game ignores votes outside of a round
+	ch, ok := b.channels[msg.Chat.ID]
+	if !ok || len(ch.quorumPlayer) < ch.quorum() {
+		return true
+	}
+
+	commandSkipCount.Inc(1)
+	ch.game.In <- fam100.SkipVoteMessage{
+		ChanID:     msg.Chat.ID,
+		Player:     model.Player{ID: model.PlayerID(msg.From.ID), Name: msg.From.FullName()},
+		ReceivedAt: msg.ReceivedAt,
+	}
+
+	return true
+}
+
 func (b *fam100Bot) handleDisabled(msg *bot.Message) bool {
 	chanID := msg.Chat.ID
 	disabledMsg, _ := repo.DefaultDB.ChannelConfig(chanID, "disabled", "")
@@ -145,7 +295,11 @@ func formatRoundText(msg fam100.QNAMessage) string {
 	var b bytes.Buffer
 	w := bufio.NewWriter(&b)
 
-	fmt.Fprintf(w, "[id: %d] %s?\n\n", msg.QuestionID, msg.QuestionText)
+	fmt.Fprintf(w, "[id: %d] %s?\n", msg.QuestionID, msg.QuestionText)
+	if msg.StrikeLimit > 0 && msg.Strikes > 0 {
+		fmt.Fprintf(w, "%s (%d/%d)\n", strings.Repeat("❌", msg.Strikes), msg.Strikes, msg.StrikeLimit)
+	}
+	fmt.Fprintf(w, "\n")
 	for i, a := range msg.Answers {
 		if a.Answered {
 			if a.Highlight {
@@ -156,6 +310,8 @@ func formatRoundText(msg fam100.QNAMessage) string {
 		} else {
 			if msg.ShowUnanswered {
 				fmt.Fprintf(w, "<b>%d. (%2d) %s \n</b>", i+1, a.Score, escape(a.Text))
+			} else if a.Hint != "" {
+				fmt.Fprintf(w, "%d. <code>%s</code>\n", i+1, escape(a.Hint))
 			} else {
 				fmt.Fprintf(w, "%d. _________________________\n", i+1)
 			}
@@ -166,21 +322,36 @@ func formatRoundText(msg fam100.QNAMessage) string {
 	return b.String()
 }
 
-func formatRankText(rank model.Rank) string {
+// formatRankText shows the ranking, mode is how the positions were numbered so a gap
+// before the next position is only marked when players are left out
+func formatRankText(rank model.Rank, mode model.RankMode) string {
 	var b bytes.Buffer
 	w := bufio.NewWriter(&b)
 
 	fmt.Fprintf(w, "\n")
-	lastPos := 0
+	// players with the same score share a position, in standard ranking the next position skips the tied players
+	lastPos, tied := 0, 0
 	if len(rank) == 0 {
-		fmt.Fprintf(w, fam100.T("Tidak ada\n"))
+		fmt.Fprint(w, fam100.T("Tidak ada\n"))
 	} else {
-		for _, ps := range rank {
-			if lastPos != 0 && lastPos+1 != ps.Position {
-				fmt.Fprintf(w, "...\n")
+		for i, ps := range rank {
+			if ps.Position == lastPos {
+				tied++
+			} else {
+				next := lastPos + tied
+				if mode == model.DenseRank {
+					next = lastPos + 1
+				}
+				if lastPos != 0 && next != ps.Position {
+					fmt.Fprintf(w, "...\n")
+				}
+				lastPos, tied = ps.Position, 1
 			}
-			fmt.Fprintf(w, "%d. (%2d) %s\n", ps.Position, ps.Score, ps.Name)
-			lastPos = ps.Position
+			mark := ""
+			if (i > 0 && rank[i-1].Position == ps.Position) || (i+1 < len(rank) && rank[i+1].Position == ps.Position) {
+				mark = "="
+			}
+			fmt.Fprintf(w, "%d%s. (%2d) %s\n", ps.Position, mark, ps.Score, ps.Name)
 		}
 	}
 	w.Flush()
@@ -188,6 +359,87 @@ func formatRankText(rank model.Rank) string {
 	return escape(b.String())
 }
 
+func formatTeamRankText(rank model.TeamRank) string {
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+
+	fmt.Fprint(w, fam100.T("\n<b>Score tim</b>:\n"))
+	for _, ts := range rank {
+		fmt.Fprintf(w, "%d. (%2d) %s\n", ts.Position, ts.Score, escape(ts.Team))
+	}
+	w.Flush()
+
+	return b.String()
+}
+
+// formatBreakdownText shows answers that got points other than the survey score
+func formatBreakdownText(details []fam100.ScoreDetail) string {
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+
+	for _, d := range details {
+		if d.Points == d.Base {
+			continue
+		}
+		fmt.Fprintf(w, "%s: %s %d → %d", escape(d.Name), escape(d.Answer), d.Base, d.Points)
+		if d.Reason != "" {
+			fmt.Fprintf(w, " (%s)", escape(d.Reason))
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	w.Flush()
+	if b.Len() == 0 {
+		return ""
+	}
+
+	return b.String() + "\n"
+}
+
+// formatRecapText shows the MVP, the fastest answer and the answers nobody found of the round
+func formatRecapText(recap fam100.RoundRecap) string {
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+
+	if recap.MVP.PlayerID != "" {
+		fmt.Fprintf(w, fam100.T("⭐ MVP: <b>%s</b> (%d poin)\n"), escape(recap.MVP.Name), recap.MVP.Score)
+	}
+	if f := recap.Fastest; f.Player.ID != "" {
+		fmt.Fprintf(w, fam100.T("⚡ Tercepat: <b>%s</b> %s (%s)\n"), escape(f.Player.Name), escape(f.Answer), f.Elapsed.Round(100*time.Millisecond))
+	}
+	if len(recap.Unanswered) > 0 {
+		fmt.Fprintf(w, fam100.T("❓ Tidak ditemukan: %s\n"), escape(strings.Join(recap.Unanswered, ", ")))
+	}
+	if recap.Guesses > 0 {
+		fmt.Fprintf(w, fam100.T("💬 %d tebakan\n"), recap.Guesses)
+	}
+	w.Flush()
+	if b.Len() == 0 {
+		return ""
+	}
+
+	return b.String() + "\n"
+}
+
+// parseCommand splits text into command and its arguments. Mention of this bot
+// in the command (/join@botname) is removed. cmd is empty if the text is not
+// a command or it is a command for another bot
+func parseCommand(text, botName string) (cmd string, args []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil
+	}
+
+	cmd = fields[0]
+	if i := strings.Index(cmd, "@"); i >= 0 {
+		if cmd[i+1:] != botName {
+			return "", nil
+		}
+		cmd = cmd[:i]
+	}
+
+	return cmd, fields[1:]
+}
+
 func escape(s string) string {
 	s = strings.Replace(s, "&", "&amp;", -1)
 	s = strings.Replace(s, "<", "&lt;", -1)
@@ -295,3 +547,56 @@ func rateLimited(cmd, chatID string, duration time.Duration) bool {
 	lastCmdRequest[chatID] = now
 	return false
 }
+
+// cmdCheaters reports players of a channel that were flagged for flooding or inhumanly fast answers
+func (b *fam100Bot) cmdCheaters(msg *bot.Message) bool {
+	fields := strings.Fields(msg.Text)
+	if len(fields) < 2 {
+		b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: "usage: `/cheaters [chanID]`", Format: bot.Markdown}
+		return true
+	}
+	chanID := fields[1]
+
+	flagged, err := repo.DefaultDB.FlaggedPlayers(chanID)
+	if err != nil {
+		b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: "cheaters failed. " + err.Error(), Format: bot.Text}
+		return true
+	}
+
+	type suspect struct {
+		id      model.PlayerID
+		total   int
+		reasons []string
+	}
+	suspects := make([]suspect, 0, len(flagged))
+	for id, reasons := range flagged {
+		s := suspect{id: id}
+		for reason, count := range reasons {
+			s.total += count
+			s.reasons = append(s.reasons, fmt.Sprintf("%s: %d", reason, count))
+		}
+		sort.Strings(s.reasons)
+		suspects = append(suspects, s)
+	}
+	sort.Slice(suspects, func(i, j int) bool {
+		if suspects[i].total != suspects[j].total {
+			return suspects[i].total > suspects[j].total
+		}
+		return suspects[i].id < suspects[j].id
+	})
+	if len(suspects) > 50 {
+		suspects = suspects[:50]
+	}
+
+	lines := []string{fmt.Sprintf("%d suspected players in %s:", len(flagged), chanID)}
+	for _, s := range suspects {
+		name := string(s.id)
+		if ps, err := repo.DefaultDB.PlayerScore(s.id); err == nil && ps.Name != "" {
+			name = fmt.Sprintf("%s (%s)", ps.Name, s.id)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s", name, strings.Join(s.reasons, ", ")))
+	}
+	b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: strings.Join(lines, "\n"), Format: bot.Text}
+
+	return true
+}
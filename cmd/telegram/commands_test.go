@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yulrizka/fam100/model"
+)
+
+func TestFormatRankText(t *testing.T) {
+	rank := func(positions ...int) model.Rank {
+		r := make(model.Rank, len(positions))
+		for i, pos := range positions {
+			r[i] = model.PlayerScore{PlayerID: model.PlayerID(rune('a' + i)), Name: "p", Score: 10 - pos, Position: pos}
+		}
+		return r
+	}
+	tests := []struct {
+		name string
+		rank model.Rank
+		mode model.RankMode
+		gap  bool
+	}{
+		{"dense ties", rank(1, 1, 2, 3), model.DenseRank, false},
+		{"dense gap", rank(1, 1, 3), model.DenseRank, true},
+		{"standard ties", rank(1, 1, 3), model.StandardRank, false},
+		{"standard gap", rank(1, 1, 4), model.StandardRank, true},
+	}
+	for _, tt := range tests {
+		text := formatRankText(tt.rank, tt.mode)
+		if want, got := tt.gap, strings.Contains(text, "..."); want != got {
+			t.Errorf("%s: gap want %t got %t\n%s", tt.name, want, got, text)
+		}
+		if !strings.Contains(text, "1=. ") {
+			t.Errorf("%s: tied players should be marked\n%s", tt.name, text)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// today returns the day of the daily challenge
+func today() string {
+	return fam100.Day(time.Now(), defaultLocation())
+}
+
+// dailyCompleted replies to the player if the daily challenge of today was completed
+func (b *fam100Bot) dailyCompleted(msg *bot.Message, day string) bool {
+	done, err := repo.DefaultDB.DailyCompleted(day, model.PlayerID(msg.From.ID))
+	if err != nil {
+		log.Error("failed to check daily challenge", zap.String("chanID", msg.Chat.ID), zap.Error(err))
+		return false
+	}
+	if done {
+		text := fmt.Sprintf(fam100.T("%s sudah menyelesaikan tantangan hari ini, coba lagi besok"), escape(msg.From.FullName()))
+		b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(10 * time.Second)}
+	}
+
+	return done
+}
+
+// cmdDaily handles "/daily [score]". Every game of the day plays the same questions and
+// each player can complete the challenge once. In private chat the player plays alone,
+// in a group the challenge is played like a normal game
+func (b *fam100Bot) cmdDaily(msg *bot.Message) bool {
+	defer cmdDailyTimer.UpdateSince(time.Now())
+
+	if b.handleDisabled(msg) {
+		return true
+	}
+
+	_, args := parseCommand(msg.Text, b.name)
+	if len(args) > 0 && args[0] == "score" {
+		return b.dailyScore(msg)
+	}
+
+	chanID := msg.Chat.ID
+	if _, ok := b.channels[chanID]; ok {
+		return true
+	}
+	if b.forward(msg) {
+		return true
+	}
+	day := today()
+	if b.dailyCompleted(msg, day) {
+		return true
+	}
+	commandDailyCount.Inc(1)
+
+	if msg.Chat.Type == bot.Private {
+		ch := b.newPractice(msg, b.qnaDB)
+		if ch == nil {
+			return true
+		}
+		ch.game.SetDaily(day)
+		ch.game.Start()
+		log.Info("Daily challenge started", zap.String("chanID", chanID), zap.String("day", day))
+		return true
+	}
+
+	ch := b.openLobby(chanID, msg.Chat.Title)
+	if ch == nil {
+		return true
+	}
+	ch.wait = 0
+	ch.game.SetDaily(day)
+	ch.startQuorumTimer(ch.quorumWait(), b.out)
+	text := fmt.Sprintf(fam100.T("<b>Tantangan harian %s</b>, pertanyaan sama untuk semua. Ketik /join untuk ikut"), day)
+	b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(ch.quorumWait())}
+	log.Info("Daily challenge lobby", zap.String("chanID", chanID), zap.String("day", day))
+
+	// the player who asked for the challenge joins the lobby
+	return b.cmdJoin(msg)
+}
+
+// dailyScore shows the ranking of today and all days of the daily challenge
+func (b *fam100Bot) dailyScore(msg *bot.Message) bool {
+	if rateLimited("daily", msg.Chat.ID, cmdRateDelay) {
+		return true
+	}
+
+	day := today()
+	rank, err := repo.DefaultDB.DailyRanking(day, 10)
+	if err != nil {
+		log.Error("getting daily ranking failed", zap.String("chanID", msg.Chat.ID), zap.Error(err))
+		return true
+	}
+	total, err := repo.DefaultDB.DailyTotalRanking(10)
+	if err != nil {
+		log.Error("getting daily total ranking failed", zap.String("chanID", msg.Chat.ID), zap.Error(err))
+		return true
+	}
+
+	text := fmt.Sprintf(fam100.T("<b>Tantangan harian %s:</b>\n"), day) + formatRankText(rank, model.StandardRank)
+	text += fam100.T("\n<b>Sepanjang masa:</b>\n") + formatRankText(total, model.StandardRank)
+	b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+
+	return true
+}
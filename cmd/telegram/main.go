@@ -40,6 +40,26 @@ var (
 	plugin               = fam100Bot{}
 	outboxWorker         = 0
 	profile              = false
+	resumeMaxAge         = 600
+	instanceID           = ""
+	leaseTTLSecond       = 30
+	leaseTTL             time.Duration
+	webhookURLs          = ""
+	webhookSecret        = ""
+	webhookEvents        = ""
+	webhookWorker        = 4
+	webhookQueueSize     = 10000
+	answerRefill         = 500
+	minAnswerTime        = 1000
+	autoplayIdle         = 10
+	autoplayDelay        = 15 * time.Second
+	timezone             = "Asia/Jakarta"
+	scheduleWaitMinute   = 10
+	scheduleWait         time.Duration
+	scheduleInterval     = 30 * time.Second
+	forfeitMinute        = 60
+	tournamentForfeit    time.Duration
+	apiAddr              = ""
 )
 
 // compiled time information
@@ -73,6 +93,21 @@ func main() {
 	flag.IntVar(&httpTimeout, "httpTimeout", 10, "http timeout in Second")
 	flag.IntVar(&outboxWorker, "outboxWorker", 0, "telegram outbox sender worker")
 	flag.BoolVar(&profile, "profile", false, "open go http profiler endpoint")
+	flag.StringVar(&fam100.JournalDir, "journal", "", "game journal directory, empty to disable")
+	flag.IntVar(&resumeMaxAge, "resumeMaxAge", 600, "resume game interrupted by restart within this age in second, 0 to disable")
+	flag.StringVar(&instanceID, "instance", "", "unique id of this instance to shard channels with other instances, empty to disable")
+	flag.IntVar(&leaseTTLSecond, "leaseTTL", 30, "channel lease ttl in second when sharding is enabled")
+	flag.StringVar(&webhookURLs, "webhook", "", "comma separated webhook urls that receive events of all games")
+	flag.StringVar(&webhookSecret, "webhookSecret", "", "secret to sign webhook payloads")
+	flag.StringVar(&webhookEvents, "webhookEvents", "", "comma separated webhook events to send, empty for all")
+	flag.IntVar(&fam100.AnswerBurst, "answerBurst", 5, "answers a player can send at once, 0 to disable rate limit")
+	flag.IntVar(&answerRefill, "answerRefill", 500, "time to regain one answer in millisecond")
+	flag.IntVar(&minAnswerTime, "minAnswerTime", 1000, "flag correct answer faster than this in millisecond, 0 to disable")
+	flag.IntVar(&autoplayIdle, "autoplayIdle", 10, "minutes without activity before auto-play stops")
+	flag.StringVar(&timezone, "timezone", "Asia/Jakarta", "default time zone of channel schedules and leaderboard periods")
+	flag.IntVar(&scheduleWaitMinute, "scheduleWait", 10, "minutes a scheduled lobby waits for quorum")
+	flag.IntVar(&forfeitMinute, "tournamentForfeit", 60, "minutes after the match start a channel that did not finish loses the match")
+	flag.StringVar(&apiAddr, "api", "", "http api listen address, empty to disable")
 	logLevel := zap.LevelFlag("v", zap.InfoLevel, "log level: all, debug, info, warn, error, panic, fatal, none")
 	flag.Parse()
 
@@ -121,6 +156,15 @@ func main() {
 
 	http.DefaultClient.Timeout = time.Duration(httpTimeout) * time.Second
 	fam100.RoundDuration = time.Duration(roundDuration) * time.Second
+	leaseTTL = time.Duration(leaseTTLSecond) * time.Second
+	if webhookURLs != "" && webhookSecret == "" {
+		log.Fatal("webhookSecret can not be empty when webhook is set")
+	}
+	fam100.AnswerRefill = time.Duration(answerRefill) * time.Millisecond
+	fam100.MinAnswerTime = time.Duration(minAnswerTime) * time.Millisecond
+	scheduleWait = time.Duration(scheduleWaitMinute) * time.Minute
+	tournamentForfeit = time.Duration(forfeitMinute) * time.Minute
+	repo.Location = defaultLocation()
 
 	// Initialize questions database
 	dbPath := "qna/famili100.txt"
@@ -148,6 +192,7 @@ func main() {
 		bot.OutboxWorker = outboxWorker
 	}
 	plugin.qnaDB = qnaDB
+	plugin.instance = instanceID
 
 	log.Info("Question limit ", zap.Int("fam100.DefaultQuestionLimit", fam100.DefaultQuestionLimit))
 
@@ -156,6 +201,10 @@ func main() {
 		log.Fatal("Failed loading DB", zap.Error(err))
 	}
 
+	if apiAddr != "" {
+		go serveAPI(apiAddr)
+	}
+
 	// Initialize telegram and plugin
 	startedAt = time.Now()
 	telegram, err := bot.NewTelegram(ctx, key)
@@ -32,11 +32,10 @@ func Test_Main(t *testing.T) {
 }
 
 func testQuorumShouldStartGame(t *testing.T) {
-	questionDB, err := qna.NewBolt("../qna/test.db")
+	questionDB, err := qna.NewText("../qna/questions.txt")
 	if err != nil {
 		t.Fatalf("failed to load questions: %v", err)
 	}
-	defer questionDB.Close()
 
 	oMinQuorum := minQuorum
 	defer func() {
@@ -35,6 +35,36 @@ var (
 	gameStartedCount     = metrics.NewRegisteredCounter("game.started.count", metrics.DefaultRegistry)
 	gameFinishedCount    = metrics.NewRegisteredCounter("game.finished.count", metrics.DefaultRegistry)
 	answerCorrectCount   = metrics.NewRegisteredCounter("answer.correct.count", metrics.DefaultRegistry)
+	commandSkipCount     = metrics.NewRegisteredCounter("command.skip.count", metrics.DefaultRegistry)
+	roundSkippedCount    = metrics.NewRegisteredCounter("round.skipped.count", metrics.DefaultRegistry)
+	commandStopCount     = metrics.NewRegisteredCounter("command.stop.count", metrics.DefaultRegistry)
+	autoplayCount        = metrics.NewRegisteredCounter("game.autoplay.count", metrics.DefaultRegistry)
+
+	commandScheduleCount = metrics.NewRegisteredCounter("command.schedule.count", metrics.DefaultRegistry)
+	scheduledGameCount   = metrics.NewRegisteredCounter("game.scheduled.count", metrics.DefaultRegistry)
+
+	commandPracticeCount      = metrics.NewRegisteredCounter("command.practice.count", metrics.DefaultRegistry)
+	commandPracticeScoreCount = metrics.NewRegisteredCounter("command.practiceScore.count", metrics.DefaultRegistry)
+
+	commandBracketCount  = metrics.NewRegisteredCounter("command.bracket.count", metrics.DefaultRegistry)
+	tournamentMatchCount = metrics.NewRegisteredCounter("tournament.match.count", metrics.DefaultRegistry)
+
+	commandDailyCount = metrics.NewRegisteredCounter("command.daily.count", metrics.DefaultRegistry)
+
+	commandBadgesCount = metrics.NewRegisteredCounter("command.badges.count", metrics.DefaultRegistry)
+	badgeAwardedCount  = metrics.NewRegisteredCounter("badge.awarded.count", metrics.DefaultRegistry)
+
+	commandMeCount = metrics.NewRegisteredCounter("command.me.count", metrics.DefaultRegistry)
+
+	commandRatingCount = metrics.NewRegisteredCounter("command.rating.count", metrics.DefaultRegistry)
+	gameRatedCount     = metrics.NewRegisteredCounter("game.rated.count", metrics.DefaultRegistry)
+
+	gameResumedCount  = metrics.NewRegisteredCounter("game.resumed.count", metrics.DefaultRegistry)
+	gameCanceledCount = metrics.NewRegisteredCounter("game.canceled.count", metrics.DefaultRegistry)
+
+	leaseAcquiredCount    = metrics.NewRegisteredCounter("lease.acquired.count", metrics.DefaultRegistry)
+	leaseLostCount        = metrics.NewRegisteredCounter("lease.lost.count", metrics.DefaultRegistry)
+	messageForwardedCount = metrics.NewRegisteredCounter("message.forwarded.count", metrics.DefaultRegistry)
 
 	channelTotal    = metrics.NewRegisteredGauge("channel.total", metrics.DefaultRegistry)
 	playerTotal     = metrics.NewRegisteredGauge("player.total", metrics.DefaultRegistry)
@@ -45,6 +75,23 @@ var (
 	cmdJoinTimer  = metrics.NewRegisteredTimer("command.join.ns", metrics.DefaultRegistry)
 	cmdScoreTimer = metrics.NewRegisteredTimer("command.score.ns", metrics.DefaultRegistry)
 	cmdHelpTimer  = metrics.NewRegisteredTimer("command.help.ns", metrics.DefaultRegistry)
+	cmdSkipTimer  = metrics.NewRegisteredTimer("command.skip.ns", metrics.DefaultRegistry)
+	cmdStopTimer  = metrics.NewRegisteredTimer("command.stop.ns", metrics.DefaultRegistry)
+
+	cmdScheduleTimer = metrics.NewRegisteredTimer("command.schedule.ns", metrics.DefaultRegistry)
+
+	cmdPracticeTimer      = metrics.NewRegisteredTimer("command.practice.ns", metrics.DefaultRegistry)
+	cmdPracticeScoreTimer = metrics.NewRegisteredTimer("command.practiceScore.ns", metrics.DefaultRegistry)
+
+	cmdBracketTimer = metrics.NewRegisteredTimer("command.bracket.ns", metrics.DefaultRegistry)
+
+	cmdDailyTimer = metrics.NewRegisteredTimer("command.daily.ns", metrics.DefaultRegistry)
+
+	cmdBadgesTimer = metrics.NewRegisteredTimer("command.badges.ns", metrics.DefaultRegistry)
+
+	cmdMeTimer = metrics.NewRegisteredTimer("command.me.ns", metrics.DefaultRegistry)
+
+	cmdRatingTimer = metrics.NewRegisteredTimer("command.rating.ns", metrics.DefaultRegistry)
 
 	mainHandleMigrationTimer = metrics.NewRegisteredTimer("main.handleMigration.ns", metrics.DefaultRegistry)
 	mainHandleMessageTimer   = metrics.NewRegisteredTimer("main.handleMessage.ns", metrics.DefaultRegistry)
@@ -57,10 +104,40 @@ var (
 	mainHandleChannelsTimer = metrics.NewRegisteredTimer("main.handleChannels.ns", metrics.DefaultRegistry)
 	// handle broadcast
 	mainHandleBrodcastTimer = metrics.NewRegisteredTimer("main.handleBrodcast.ns", metrics.DefaultRegistry)
+	// handle webhooks
+	mainHandleWebhooksTimer = metrics.NewRegisteredTimer("main.handleWebhooks.ns", metrics.DefaultRegistry)
+	// handle cheaters
+	mainHandleCheatersTimer = metrics.NewRegisteredTimer("main.handleCheaters.ns", metrics.DefaultRegistry)
+	// handle tournament
+	mainHandleTournamentTimer = metrics.NewRegisteredTimer("main.handleTournament.ns", metrics.DefaultRegistry)
+	// handle season
+	mainHandleSeasonTimer = metrics.NewRegisteredTimer("main.handleSeason.ns", metrics.DefaultRegistry)
 	// handle join
 	mainHandleJoinTimer = metrics.NewRegisteredTimer("main.handleJoin.ns", metrics.DefaultRegistry)
 	// handle score
 	mainHandleScoreTimer = metrics.NewRegisteredTimer("main.handleScore.ns", metrics.DefaultRegistry)
+	// handle skip
+	mainHandleSkipTimer = metrics.NewRegisteredTimer("main.handleSkip.ns", metrics.DefaultRegistry)
+	// handle stop
+	mainHandleStopTimer = metrics.NewRegisteredTimer("main.handleStop.ns", metrics.DefaultRegistry)
+	// handle schedule
+	mainHandleScheduleTimer = metrics.NewRegisteredTimer("main.handleSchedule.ns", metrics.DefaultRegistry)
+	// handle practice
+	mainHandlePracticeTimer = metrics.NewRegisteredTimer("main.handlePractice.ns", metrics.DefaultRegistry)
+	// handle practice score
+	mainHandlePracticeScoreTimer = metrics.NewRegisteredTimer("main.handlePracticeScore.ns", metrics.DefaultRegistry)
+	// handle categories
+	mainHandleCategoriesTimer = metrics.NewRegisteredTimer("main.handleCategories.ns", metrics.DefaultRegistry)
+	// handle bracket
+	mainHandleBracketTimer = metrics.NewRegisteredTimer("main.handleBracket.ns", metrics.DefaultRegistry)
+	// handle daily
+	mainHandleDailyTimer = metrics.NewRegisteredTimer("main.handleDaily.ns", metrics.DefaultRegistry)
+	// handle badges
+	mainHandleBadgesTimer = metrics.NewRegisteredTimer("main.handleBadges.ns", metrics.DefaultRegistry)
+	// handle me
+	mainHandleMeTimer = metrics.NewRegisteredTimer("main.handleMe.ns", metrics.DefaultRegistry)
+	// handle rating
+	mainHandleRatingTimer = metrics.NewRegisteredTimer("main.handleRating.ns", metrics.DefaultRegistry)
 	// handle help
 	mainHandleHelpTimer = metrics.NewRegisteredTimer("main.handleHelp.ns", metrics.DefaultRegistry)
 	// handle privateChat
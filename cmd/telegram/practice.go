@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/qna"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// practiceQuorum is the number of players of a practice game
+const practiceQuorum = 1
+
+// quorum returns the number of players needed to start the game
+func (c *channel) quorum() int {
+	if c.practice {
+		return practiceQuorum
+	}
+	return minQuorum
+}
+
+// practiceQuestions returns questions of the categories, all questions if categories is empty
+func (b *fam100Bot) practiceQuestions(categories []string) (qna.Provider, error) {
+	if len(categories) == 0 {
+		return b.qnaDB, nil
+	}
+	c, ok := b.qnaDB.(qna.Categorizer)
+	if !ok || len(c.Categories()) == 0 {
+		return nil, errors.New(fam100.T("Kategori tidak tersedia"))
+	}
+	return c.InCategory(categories...)
+}
+
+// cmdPractice handles "/practice [category, ...]" in private chat. The player plays alone against the clock
+func (b *fam100Bot) cmdPractice(msg *bot.Message) bool {
+	defer cmdPracticeTimer.UpdateSince(time.Now())
+
+	chanID := msg.Chat.ID
+	if _, ok := b.channels[chanID]; ok {
+		return true
+	}
+	if b.forward(msg) {
+		return true
+	}
+
+	_, args := parseCommand(msg.Text, b.name)
+	var categories []string
+	for _, c := range strings.Split(strings.Join(args, " "), ",") {
+		if c = qna.NormalizeCategory(c); c != "" {
+			categories = append(categories, c)
+		}
+	}
+	questions, err := b.practiceQuestions(categories)
+	if err != nil {
+		text := escape(err.Error())
+		if list := b.categoriesText(); list != "" {
+			text += "\n\n" + list
+		}
+		b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+		return true
+	}
+
+	ch := b.newPractice(msg, questions)
+	if ch == nil {
+		return true
+	}
+	commandPracticeCount.Inc(1)
+	ch.game.Start()
+	log.Info("Practice started", zap.String("chanID", chanID), zap.String("categories", strings.Join(categories, ",")))
+
+	return true
+}
+
+// newPractice creates a practice game of the private chat, it returns nil if the
+// chat is owned by another instance
+func (b *fam100Bot) newPractice(msg *bot.Message, questions qna.Provider) *channel {
+	chanID := msg.Chat.ID
+	lease, owned := b.claim(chanID)
+	if !owned {
+		b.forward(msg)
+		return nil
+	}
+
+	gameIn := make(chan fam100.Message, gameInBufferSize)
+	game, err := fam100.NewGame(chanID, msg.From.FullName(), gameIn, questions)
+	if err != nil {
+		log.Error("creating a game", zap.String("chanID", chanID))
+		b.release(chanID, lease)
+		return nil
+	}
+	game.Fence = lease
+	game.Practice = true
+	b.subscribe(game)
+
+	ch := &channel{
+		ID:           chanID,
+		Name:         msg.From.FullName(),
+		game:         game,
+		lease:        lease,
+		practice:     true,
+		quorumPlayer: map[string]bool{msg.From.ID: true},
+		players:      map[string]string{msg.From.ID: msg.From.FullName()},
+		teams:        make(map[string]string),
+	}
+	b.channels[chanID] = ch
+
+	return ch
+}
+
+// cmdPracticeScore handles "/score" in private chat, shows the practice ranking
+func (b *fam100Bot) cmdPracticeScore(msg *bot.Message) bool {
+	defer cmdPracticeScoreTimer.UpdateSince(time.Now())
+
+	if rateLimited("score", msg.Chat.ID, cmdRateDelay) {
+		return true
+	}
+
+	commandPracticeScoreCount.Inc(1)
+	chanID := msg.Chat.ID
+	rank, err := repo.DefaultDB.PracticeRanking(20)
+	if err != nil {
+		log.Error("getting practice ranking failed", zap.String("chanID", chanID), zap.Error(err))
+		return true
+	}
+
+	text := fam100.T("<b>Top Score Latihan:</b>\n") + formatRankText(rank, model.StandardRank)
+	if ps, err := repo.DefaultDB.PracticeScore(model.PlayerID(msg.From.ID)); err == nil {
+		text += fmt.Sprintf(fam100.T("\nScore terbaik kamu: %d (peringkat %d)"), ps.Score, ps.Position)
+	}
+	b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+
+	return true
+}
+
+// cmdCategories handles "/categories" in private chat
+func (b *fam100Bot) cmdCategories(msg *bot.Message) bool {
+	text := b.categoriesText()
+	if text == "" {
+		text = fam100.T("Kategori tidak tersedia")
+	}
+	b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+
+	return true
+}
+
+// categoriesText lists question categories, empty if questions have no category
+func (b *fam100Bot) categoriesText() string {
+	c, ok := b.qnaDB.(qna.Categorizer)
+	if !ok || len(c.Categories()) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(fam100.T("Kategori: %s\nContoh: <code>/practice %s</code>"), escape(strings.Join(c.Categories(), ", ")), escape(c.Categories()[0]))
+}
+
+// handlePracticeAnswer passes a private message to the practice game of the chat
+func (b *fam100Bot) handlePracticeAnswer(msg *bot.Message) {
+	chanID := msg.Chat.ID
+	ch, ok := b.channels[chanID]
+	if !ok {
+		b.forward(msg)
+		return
+	}
+	if !ch.practice || ch.game.State == fam100.Created {
+		return
+	}
+
+	ch.game.In <- fam100.TextMessage{
+		Player:     model.Player{ID: model.PlayerID(msg.From.ID), Name: msg.From.FullName()},
+		Text:       msg.Text,
+		ReceivedAt: msg.ReceivedAt,
+	}
+}
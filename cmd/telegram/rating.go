@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/rating"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// rate updates the rating of the players from the final ranking of a game
+func rate(e fam100.Event) error {
+	msg, ok := e.(fam100.RankMessage)
+	if !ok || !msg.Final || len(msg.Rank) < 2 {
+		return nil
+	}
+
+	ids := make([]model.PlayerID, 0, len(msg.Rank))
+	for _, ps := range msg.Rank {
+		ids = append(ids, ps.PlayerID)
+	}
+	update := func(ratings map[model.PlayerID]model.Rating) map[model.PlayerID]model.Rating {
+		return rating.Update(msg.Rank, ratings)
+	}
+	if err := repo.DefaultDB.UpdateRatings(ids, rating.ProvisionalGames, update); err != nil {
+		return errors.Wrap(err, "failed to update ratings")
+	}
+	gameRatedCount.Inc(1)
+
+	return nil
+}
+
+// cmdRating handles "/rating", shows the players with the highest rating and the rating of the player
+func (b *fam100Bot) cmdRating(msg *bot.Message) bool {
+	defer cmdRatingTimer.UpdateSince(time.Now())
+
+	if rateLimited("rating", msg.Chat.ID+msg.From.ID, cmdRateDelay) {
+		return true
+	}
+
+	commandRatingCount.Inc(1)
+	chanID := msg.Chat.ID
+	rank, err := repo.DefaultDB.RatingRanking(10)
+	if err != nil {
+		log.Error("getting rating ranking failed", zap.String("chanID", chanID), zap.Error(err))
+		return true
+	}
+	text := fam100.T("<b>Rating Tertinggi:</b>\n") + formatRankText(rank, model.StandardRank)
+
+	playerID := model.PlayerID(msg.From.ID)
+	ratings, err := repo.DefaultDB.Ratings([]model.PlayerID{playerID})
+	if err != nil {
+		log.Error("getting rating failed", zap.String("chanID", chanID), zap.Error(err))
+		return true
+	}
+	r, ok := ratings[playerID]
+	switch {
+	case !ok:
+		text += fmt.Sprintf(fam100.T("\n%s belum punya rating, mainkan game dengan pemain lain"), escape(msg.From.FullName()))
+	case rating.Provisional(r):
+		text += fmt.Sprintf(fam100.T("\nRating %s: %.0f (sementara, %d dari %d game)"), escape(msg.From.FullName()), math.Round(r.Value), r.Games, rating.ProvisionalGames)
+	default:
+		text += fmt.Sprintf(fam100.T("\nRating %s: %.0f (%d game)"), escape(msg.From.FullName()), math.Round(r.Value), r.Games)
+	}
+	b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+
+	return true
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/repo"
+	"github.com/yulrizka/fam100/schedule"
+)
+
+var (
+	// scheduleLimit is the maximum schedules of a channel
+	scheduleLimit = 5
+	// scheduleMinInterval is the minimum time between two scheduled games
+	scheduleMinInterval = 30 * time.Minute
+)
+
+// schedulerLease is the lease of the instance that evaluates the schedules
+const schedulerLease = "scheduler"
+
+// chatMember is implemented by clients that can look up the status of a chat member
+type chatMember interface {
+	Member(ctx context.Context, chatID, userID string) (*bot.TChatMember, error)
+}
+
+// isChatAdmin returns whether the user is the creator or an administrator of the chat
+func (b *fam100Bot) isChatAdmin(chatID, userID string) bool {
+	if userID == adminID {
+		return true
+	}
+	cl, ok := b.cl.(chatMember)
+	if !ok {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(httpTimeout)*time.Second)
+	defer cancel()
+
+	member, err := cl.Member(ctx, chatID, userID)
+	if err != nil {
+		log.Error("failed to get chat member", zap.String("chanID", chatID), zap.String("playerID", userID), zap.Error(err))
+		return false
+	}
+
+	return member.Status == "creator" || member.Status == "administrator"
+}
+
+// channelLocation returns the time zone of the channel, set with "/schedule tz"
+func channelLocation(chanID string) *time.Location {
+	name, err := repo.DefaultDB.ChannelConfig(chanID, "timezone", "")
+	if err != nil || name == "" {
+		return defaultLocation()
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Error("invalid channel time zone", zap.String("chanID", chanID), zap.String("timezone", name), zap.Error(err))
+		return defaultLocation()
+	}
+
+	return loc
+}
+
+// defaultLocation returns the time zone of the -timezone flag
+func defaultLocation() *time.Location {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		log.Error("invalid time zone", zap.String("timezone", timezone), zap.Error(err))
+		return time.Local
+	}
+
+	return loc
+}
+
+// parseSchedule parses a schedule of the channel and rejects one that plays too
+// often together with the existing schedules of the channel
+func parseSchedule(spec string, existing []string, loc *time.Location) (*schedule.Schedule, error) {
+	s, err := schedule.Parse(spec, loc)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if s.Next(now).IsZero() {
+		return nil, fmt.Errorf("schedule %q never happens", spec)
+	}
+
+	// the next games of all schedules, a game closer than the minimum interval to the
+	// previous one is always a real conflict even if later games were not collected
+	games := nextGames(s, now)
+	for _, e := range existing {
+		es, err := schedule.Parse(e, loc)
+		if err != nil || es.Spec == s.Spec {
+			continue
+		}
+		games = append(games, nextGames(es, now)...)
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].Before(games[j]) })
+	for i := 1; i < len(games); i++ {
+		if games[i].Sub(games[i-1]) < scheduleMinInterval {
+			return nil, fmt.Errorf("schedule %q is more often than every %s", spec, scheduleMinInterval)
+		}
+	}
+
+	return s, nil
+}
+
+// nextGames returns the next game times of the schedule
+func nextGames(s *schedule.Schedule, from time.Time) []time.Time {
+	var games []time.Time
+	for at := s.Next(from); !at.IsZero() && len(games) < 10; at = s.Next(at) {
+		games = append(games, at)
+	}
+
+	return games
+}
+
+// scheduler returns whether this instance evaluates the schedules. When channels
+// are sharded only the instance that holds the scheduler lease does
+func (b *fam100Bot) scheduler() bool {
+	if !b.sharded() {
+		return true
+	}
+	// outlives the interval so the owner keeps it from one tick to the next
+	token, err := repo.DefaultDB.AcquireLease(schedulerLease, b.instance, 2*scheduleInterval)
+	if err != nil {
+		log.Error("failed to acquire scheduler lease", zap.Error(err))
+		return false
+	}
+
+	return token != 0
+}
+
+// runSchedules opens a lobby in channels that have a schedule between from and to
+func (b *fam100Bot) runSchedules(from, to time.Time) {
+	if !b.scheduler() {
+		return
+	}
+	schedules, err := repo.DefaultDB.Schedules()
+	if err != nil {
+		log.Error("failed to load schedules", zap.Error(err))
+		return
+	}
+
+	for chanID, specs := range schedules {
+		if _, exists := b.channels[chanID]; exists {
+			continue
+		}
+		loc := channelLocation(chanID)
+		for spec, chanName := range specs {
+			s, err := schedule.Parse(spec, loc)
+			if err != nil {
+				log.Error("invalid schedule", zap.String("chanID", chanID), zap.String("spec", spec), zap.Error(err))
+				continue
+			}
+			if at := s.Next(from); at.IsZero() || at.After(to) {
+				continue
+			}
+			b.openScheduledLobby(chanID, chanName)
+			// one lobby even when several schedules are due
+			break
+		}
+	}
+}
+
+// openLobby creates a game in the channel that waits for players to join. It returns
+// nil if the channel is busy or owned by another instance
+func (b *fam100Bot) openLobby(chanID, chanName string) *channel {
+	if _, exists := b.channels[chanID]; exists {
+		return nil
+	}
+	if disabled, _ := repo.DefaultDB.ChannelConfig(chanID, "disabled", ""); disabled != "" {
+		return nil
+	}
+	lease, owned := b.claim(chanID)
+	if !owned {
+		return nil
+	}
+
+	gameIn := make(chan fam100.Message, gameInBufferSize)
+	game, err := fam100.NewGame(chanID, chanName, gameIn, b.qnaDB)
+	if err != nil {
+		log.Error("creating a game", zap.String("chanID", chanID))
+		b.release(chanID, lease)
+		return nil
+	}
+	game.Fence = lease
+	b.subscribe(game)
+
+	ch := &channel{
+		ID:           chanID,
+		Name:         chanName,
+		game:         game,
+		lease:        lease,
+		wait:         scheduleWait,
+		quorumPlayer: make(map[string]bool),
+		players:      make(map[string]string),
+		teams:        make(map[string]string),
+	}
+	b.channels[chanID] = ch
+
+	return ch
+}
+
+// openScheduledLobby announces the scheduled game and waits for players to join
+func (b *fam100Bot) openScheduledLobby(chanID, chanName string) {
+	ch := b.openLobby(chanID, chanName)
+	if ch == nil {
+		return
+	}
+	ch.saveLobby()
+	ch.startQuorumTimer(ch.quorumWait(), b.out)
+	scheduledGameCount.Inc(1)
+
+	text := fmt.Sprintf(fam100.T("Game terjadwal dimulai! Ketik /join untuk ikut, butuh %d orang dalam %s"), minQuorum, ch.quorumWait())
+	b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(ch.quorumWait())}
+	log.Info("Scheduled lobby", zap.String("chanID", chanID))
+}
+
+// cmdSchedule handles "/schedule [add|remove|tz] [spec]", only chat admins can manage the schedules
+func (b *fam100Bot) cmdSchedule(msg *bot.Message) bool {
+	defer cmdScheduleTimer.UpdateSince(time.Now())
+
+	chanID := msg.Chat.ID
+	if !b.isChatAdmin(chanID, msg.From.ID) {
+		return true
+	}
+
+	commandScheduleCount.Inc(1)
+	_, args := parseCommand(msg.Text, b.name)
+	reply := func(text string) {
+		b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(20 * time.Second)}
+	}
+	usage := fam100.T("Cara pakai:\n/schedule add 20:00\n/schedule add sat,sun 19:30\n/schedule add 0 20 * * 1-5\n/schedule remove [nomor]\n/schedule tz Asia/Jakarta")
+
+	if len(args) == 0 {
+		reply(b.scheduleList(chanID) + "\n" + usage)
+		return true
+	}
+
+	param := strings.Join(args[1:], " ")
+	switch args[0] {
+	case "add":
+		specs, err := b.channelSchedules(chanID)
+		if err != nil {
+			log.Error("failed to load schedules", zap.String("chanID", chanID), zap.Error(err))
+			return true
+		}
+		if len(specs) >= scheduleLimit {
+			reply(fmt.Sprintf(fam100.T("Maksimal %d jadwal"), scheduleLimit))
+			return true
+		}
+		s, err := parseSchedule(param, specs, channelLocation(chanID))
+		if err != nil {
+			reply(escape(err.Error()) + "\n\n" + usage)
+			return true
+		}
+		if err := repo.DefaultDB.AddSchedule(chanID, msg.Chat.Title, s.Spec); err != nil {
+			log.Error("failed to add schedule", zap.String("chanID", chanID), zap.Error(err))
+			return true
+		}
+		log.Info("Schedule added", zap.String("chanID", chanID), zap.String("spec", s.Spec), zap.String("by", msg.From.ID))
+		reply(b.scheduleList(chanID))
+
+	case "remove":
+		specs, err := b.channelSchedules(chanID)
+		if err != nil {
+			log.Error("failed to load schedules", zap.String("chanID", chanID), zap.Error(err))
+			return true
+		}
+		spec := strings.ToLower(param)
+		if i, err := strconv.Atoi(param); err == nil && i > 0 && i <= len(specs) {
+			spec = specs[i-1]
+		}
+		if err := repo.DefaultDB.RemoveSchedule(chanID, spec); err != nil {
+			log.Error("failed to remove schedule", zap.String("chanID", chanID), zap.Error(err))
+			return true
+		}
+		log.Info("Schedule removed", zap.String("chanID", chanID), zap.String("spec", spec), zap.String("by", msg.From.ID))
+		reply(b.scheduleList(chanID))
+
+	case "tz":
+		if _, err := time.LoadLocation(param); err != nil || param == "" {
+			reply(fmt.Sprintf(fam100.T("Zona waktu tidak dikenal: %s"), escape(param)))
+			return true
+		}
+		if err := repo.DefaultDB.SetChannelConfig(chanID, "timezone", param); err != nil {
+			log.Error("failed to set time zone", zap.String("chanID", chanID), zap.Error(err))
+			return true
+		}
+		reply(b.scheduleList(chanID))
+
+	default:
+		reply(usage)
+	}
+
+	return true
+}
+
+// channelSchedules returns sorted schedule specs of the channel
+func (b *fam100Bot) channelSchedules(chanID string) ([]string, error) {
+	schedules, err := repo.DefaultDB.Schedules()
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]string, 0, len(schedules[chanID]))
+	for spec := range schedules[chanID] {
+		specs = append(specs, spec)
+	}
+	sort.Strings(specs)
+
+	return specs, nil
+}
+
+// scheduleList formats the schedules of the channel with the next game time
+func (b *fam100Bot) scheduleList(chanID string) string {
+	specs, err := b.channelSchedules(chanID)
+	if err != nil {
+		log.Error("failed to load schedules", zap.String("chanID", chanID), zap.Error(err))
+		return ""
+	}
+	loc := channelLocation(chanID)
+
+	lines := []string{fmt.Sprintf(fam100.T("<b>Jadwal game</b> (%s):"), escape(loc.String()))}
+	if len(specs) == 0 {
+		lines = append(lines, fam100.T("Tidak ada"))
+	}
+	for i, spec := range specs {
+		line := fmt.Sprintf("%d. <code>%s</code>", i+1, escape(spec))
+		if s, err := schedule.Parse(spec, loc); err == nil {
+			if next := s.Next(time.Now()); !next.IsZero() {
+				line += fmt.Sprintf(" → %s", next.Format("Mon 02 Jan 15:04"))
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec     string
+		existing []string
+		valid    bool
+	}{
+		{"20:00", nil, true},
+		{"*/10 * * * *", nil, false},
+		{"20:15", []string{"20:00"}, false},
+		{"sat 20:15", []string{"20:00"}, false},
+		{"21:00", []string{"20:00", "sat 19:00"}, true},
+		{"20:00", []string{"20:00"}, true},
+	}
+	for _, tt := range tests {
+		_, err := parseSchedule(tt.spec, tt.existing, time.UTC)
+		if want, got := tt.valid, err == nil; want != got {
+			t.Errorf("schedule %q with %v valid want %t got %t (%v)", tt.spec, tt.existing, want, got, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// cmdSeason handles admin commands of seasons
+//
+//	/season
+//	/season start
+func (b *fam100Bot) cmdSeason(msg *bot.Message) bool {
+	reply := func(text string) {
+		b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: text, Format: bot.Text}
+	}
+	usage := "usage:\n/season\n/season start"
+	fields := strings.Fields(msg.Text)
+
+	if len(fields) < 2 {
+		season, err := repo.DefaultDB.Season()
+		if err != nil {
+			reply("season failed. " + err.Error())
+			return true
+		}
+		reply(fmt.Sprintf("season %d is running\n\n%s", season, usage))
+		return true
+	}
+
+	if fields[1] != "start" {
+		reply(usage)
+		return true
+	}
+	archived, err := repo.DefaultDB.StartSeason()
+	if err != nil {
+		reply("season failed. " + err.Error())
+		return true
+	}
+	log.Info("Season started", zap.Int("archived", archived))
+	reply(fmt.Sprintf("season %d archived, season %d started. awarding badges", archived, archived+1))
+
+	// awarding goes through every channel, keep it off the inbox
+	go func() {
+		awarded, err := b.awardSeason(archived, time.Now())
+		if err != nil {
+			log.Error("awarding season failed", zap.Int("season", archived), zap.Error(err))
+			reply("awarding season failed. " + err.Error())
+			return
+		}
+		reply(fmt.Sprintf("season %d: %d badges awarded", archived, awarded))
+	}()
+
+	return true
+}
+
+// awardSeason awards the placement badges of the archived season in every channel
+func (b *fam100Bot) awardSeason(season int, now time.Time) (awarded int, err error) {
+	channels, err := repo.DefaultDB.Channels()
+	if err != nil {
+		return 0, err
+	}
+	for chanID := range channels {
+		// ties may take more than the placement slots
+		rank, rankErr := repo.DefaultDB.SeasonRanking(chanID, season, 10)
+		if rankErr != nil {
+			err = rankErr
+			continue
+		}
+		awards, awardErr := b.achievements.Season(chanID, season, rank, now)
+		if awardErr != nil {
+			err = awardErr
+		}
+		b.announceAwards(awards)
+		awarded += len(awards)
+	}
+
+	return awarded, err
+}
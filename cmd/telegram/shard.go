@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// Channels can be sharded across multiple bot instances. An instance owns a
+// channel by holding its lease in redis, messages of a channel that is owned by
+// another instance are forwarded to the owner's inbox. Leases of an instance that
+// died expires, and the other instances take over the channel from its checkpoint.
+
+var closedInbox = func() chan []byte {
+	c := make(chan []byte)
+	close(c)
+	return c
+}()
+
+// sharded returns whether channel ownership is coordinated with other instances
+func (b *fam100Bot) sharded() bool {
+	return b.instance != ""
+}
+
+// claim acquires the lease of a channel. It returns the fencing token, or ok false
+// if the channel is owned by another instance
+func (b *fam100Bot) claim(chanID string) (token int64, ok bool) {
+	if !b.sharded() {
+		return 0, true
+	}
+	token, err := repo.DefaultDB.AcquireLease(chanID, b.instance, leaseTTL)
+	if err != nil {
+		log.Error("failed to acquire lease", zap.String("chanID", chanID), zap.Error(err))
+		return 0, false
+	}
+	if token == 0 {
+		return 0, false
+	}
+	leaseAcquiredCount.Inc(1)
+
+	return token, true
+}
+
+// release gives up the lease of a channel that has no game anymore
+func (b *fam100Bot) release(chanID string, token int64) {
+	if !b.sharded() || token == 0 {
+		return
+	}
+	if err := repo.DefaultDB.ReleaseLease(chanID, b.instance, token); err != nil {
+		log.Error("failed to release lease", zap.String("chanID", chanID), zap.Error(err))
+	}
+}
+
+// forward sends the message to the instance that owns the channel. It returns
+// false if the message should be handled by this instance
+func (b *fam100Bot) forward(msg *bot.Message) bool {
+	if !b.sharded() {
+		return false
+	}
+	chanID := msg.Chat.ID
+	if _, ok := b.channels[chanID]; ok {
+		return false
+	}
+	owner, err := repo.DefaultDB.LeaseOwner(chanID)
+	if err != nil {
+		log.Error("failed to get lease owner", zap.String("chanID", chanID), zap.Error(err))
+		return false
+	}
+	if owner == "" || owner == b.instance {
+		return false
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Error("failed to encode message", zap.String("chanID", chanID), zap.Error(err))
+		return true
+	}
+	if err := repo.DefaultDB.Publish(owner, data); err != nil {
+		log.Error("failed to forward message", zap.String("chanID", chanID), zap.String("owner", owner), zap.Error(err))
+		return true
+	}
+	messageForwardedCount.Inc(1)
+
+	return true
+}
+
+// renewLeases extends the lease of all local channels and drops channels whose lease was lost
+func (b *fam100Bot) renewLeases() {
+	for chanID, ch := range b.channels {
+		if ch.lease == 0 {
+			continue
+		}
+		ok, err := repo.DefaultDB.RenewLease(chanID, b.instance, ch.lease, leaseTTL)
+		if err != nil {
+			log.Error("failed to renew lease", zap.String("chanID", chanID), zap.Error(err))
+			continue
+		}
+		if !ok {
+			leaseLostCount.Inc(1)
+			log.Warn("channel lease lost", zap.String("chanID", chanID), zap.Int64("lease", ch.lease))
+			if ch.cancelTimer != nil {
+				ch.cancelTimer()
+			}
+			delete(b.channels, chanID)
+		}
+	}
+}
+
+// receiveForwarded passes messages forwarded by other instances to the inbox
+func (b *fam100Bot) receiveForwarded(ctx context.Context) {
+	for {
+		inbox, err := repo.DefaultDB.Subscribe(ctx, b.instance)
+		if err != nil {
+			log.Error("failed to subscribe inbox", zap.String("instanceID", b.instance), zap.Error(err))
+			inbox = closedInbox
+		}
+		for data := range inbox {
+			msg := new(bot.Message)
+			if err := json.Unmarshal(data, msg); err != nil {
+				log.Error("invalid forwarded message", zap.Error(err))
+				continue
+			}
+			b.in <- msg
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+			// reconnect
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/qna"
+	"github.com/yulrizka/fam100/repo"
+)
+
+func TestShardTakeover(t *testing.T) {
+	repo.RedisPrefix = "test_fam100"
+	if err := repo.DefaultDB.Init(); err != nil {
+		t.Fatalf("failed to load database: %v", err)
+	}
+	if err := repo.DefaultDB.Reset(); err != nil {
+		t.Fatalf("failed to reset database: %v", err)
+	}
+	questionDB, err := qna.NewText("../qna/questions.txt")
+	if err != nil {
+		t.Fatalf("failed to load questions: %v", err)
+	}
+
+	oMinQuorum, oLeaseTTL := minQuorum, leaseTTL
+	defer func() { minQuorum, leaseTTL = oMinQuorum, oLeaseTTL }()
+	minQuorum, leaseTTL = 3, 300*time.Millisecond
+
+	// two instances sharing one redis
+	ctxA, cancelA := context.WithCancel(context.Background())
+	defer cancelA()
+	a := &fam100Bot{name: "fam100bot", qnaDB: questionDB, instance: "a", quit: make(chan struct{})}
+	if err := a.Init(ctxA, make(chan bot.Message, 100), nil); err != nil {
+		t.Fatalf("failed to initialize bot a: %v", err)
+	}
+	b := &fam100Bot{name: "fam100bot", qnaDB: questionDB, instance: "b"}
+	bOut := make(chan bot.Message, 100)
+	if err := b.Init(context.Background(), bOut, nil); err != nil {
+		t.Fatalf("failed to initialize bot b: %v", err)
+	}
+
+	chanID := "shard"
+	join := func(to *fam100Bot, id string) {
+		to.in <- &bot.Message{
+			From: bot.User{ID: id, FirstName: "Player " + id},
+			Chat: bot.Chat{ID: chanID, Type: bot.Group},
+			Text: "/join@fam100bot",
+		}
+	}
+	owner := func(want string) func() bool {
+		return func() bool {
+			got, _ := repo.DefaultDB.LeaseOwner(chanID)
+			return got == want
+		}
+	}
+
+	// a owns the channel, join on b is forwarded to a
+	join(a, "1")
+	waitFor(t, "lease of a", owner("a"))
+	join(b, "2")
+	waitFor(t, "forwarded join", func() bool {
+		data, _ := repo.DefaultDB.Checkpoint(lobbyCheckpointKind, chanID)
+		var lc lobbyCheckpoint
+		return json.Unmarshal(data, &lc) == nil && len(lc.Players) == 2
+	})
+
+	// a died, b takes over once the lease expired
+	close(a.quit)
+	cancelA()
+	waitFor(t, "lease of b", owner("b"))
+	waitForText(t, bOut, chanID, "2 pemain sudah /join")
+
+	// resumed lobby is played on b
+	join(b, "3")
+	waitFor(t, "game on b", func() bool {
+		cp, found, _ := fam100.LoadCheckpoint(chanID)
+		return found && cp.Round == 1 && len(cp.Players) == 3
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func waitForText(t *testing.T, out chan bot.Message, chanID, text string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-out:
+			if msg.Chat.ID == chanID && strings.Contains(msg.Text, text) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %q", text)
+		}
+	}
+}
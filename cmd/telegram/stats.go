@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// handleProgress records the statistics, the rating and the achievements of players
+// from game events. It runs apart from the outbox so the repo calls never delay
+// the game messages
+func (b *fam100Bot) handleProgress() {
+	for {
+		select {
+		case <-b.quit:
+			return
+		case e := <-b.progressOut:
+			if err := b.records.Handle(e); err != nil {
+				log.Error("failed to record player stats", zap.Int64("gameID", e.EventGameID()), zap.Error(err))
+			}
+			if err := rate(e); err != nil {
+				log.Error("failed to rate players", zap.Int64("gameID", e.EventGameID()), zap.Error(err))
+			}
+			awards, err := b.achievements.Handle(e, time.Now())
+			if err != nil {
+				log.Error("failed to evaluate achievements", zap.Int64("gameID", e.EventGameID()), zap.Error(err))
+			}
+			b.announceAwards(awards)
+		}
+	}
+}
+
+// cmdMe handles "/me", shows the record of the player in the group and in all groups
+func (b *fam100Bot) cmdMe(msg *bot.Message) bool {
+	defer cmdMeTimer.UpdateSince(time.Now())
+
+	if rateLimited("me", msg.Chat.ID+msg.From.ID, cmdRateDelay) {
+		return true
+	}
+
+	commandMeCount.Inc(1)
+	chanID := msg.Chat.ID
+	playerID := model.PlayerID(msg.From.ID)
+	text := fmt.Sprintf(fam100.T("<b>Statistik %s</b>\n"), escape(msg.From.FullName()))
+	if msg.Chat.Type != bot.Private {
+		s, err := repo.DefaultDB.PlayerStats(chanID, playerID)
+		if err != nil {
+			log.Error("getting player stats failed", zap.String("chanID", chanID), zap.Error(err))
+			return true
+		}
+		text += fam100.T("\n<b>Grup ini</b>\n") + formatStatsText(s)
+	}
+	s, err := repo.DefaultDB.PlayerStats("", playerID)
+	if err != nil {
+		log.Error("getting player stats failed", zap.String("chanID", chanID), zap.Error(err))
+		return true
+	}
+	text += fam100.T("\n<b>Semua grup</b>\n") + formatStatsText(s)
+	b.out <- bot.Message{Chat: bot.Chat{ID: chanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+
+	return true
+}
+
+func formatStatsText(s model.PlayerStats) string {
+	if s.Games == 0 {
+		return fam100.T("Belum pernah bermain\n")
+	}
+
+	return fmt.Sprintf(fam100.T("Game: %d\nRonde: %d\nJawaban benar: %d\nJawaban teratas: %d\nRata-rata waktu menjawab: %s\nScore game terbaik: %d\n"),
+		s.Games, s.Rounds, s.Correct, s.TopAnswers, s.AverageAnswerTime().Round(100*time.Millisecond), s.BestGame)
+}
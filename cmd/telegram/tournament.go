@@ -0,0 +1,413 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/repo"
+	"github.com/yulrizka/fam100/tournament"
+)
+
+// championKey is the tournament result that records the champion was announced
+const championKey = "champion"
+
+// matchConfig is the channel config of the match the channel plays, "tournamentID matchID"
+const matchConfig = "tournamentMatch"
+
+// loadTournaments returns all tournaments ordered by id
+func loadTournaments() ([]*tournament.Tournament, error) {
+	raw, err := repo.DefaultDB.Tournaments()
+	if err != nil {
+		return nil, err
+	}
+
+	tournaments := make([]*tournament.Tournament, 0, len(raw))
+	for id, data := range raw {
+		t := new(tournament.Tournament)
+		if err := json.Unmarshal(data, t); err != nil {
+			log.Error("invalid tournament", zap.String("tournamentID", id), zap.Error(err))
+			continue
+		}
+		tournaments = append(tournaments, t)
+	}
+	sort.Slice(tournaments, func(i, j int) bool { return tournaments[i].ID < tournaments[j].ID })
+
+	return tournaments, nil
+}
+
+func loadTournament(id string) (*tournament.Tournament, error) {
+	data, err := repo.DefaultDB.Tournament(id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("tournament %s not found", id)
+	}
+	t := new(tournament.Tournament)
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func saveTournament(t *tournament.Tournament) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return repo.DefaultDB.SaveTournament(t.ID, data)
+}
+
+// tournamentBracket computes the bracket of a tournament from its results
+func tournamentBracket(t *tournament.Tournament) (*tournament.Bracket, map[string]int64, error) {
+	results, err := repo.DefaultDB.TournamentResults(t.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t.Bracket(results), results, nil
+}
+
+// runTournaments opens the lobby of matches that are due, forfeits channels that did
+// not finish their match in time and announces the champion
+func (b *fam100Bot) runTournaments(now time.Time) {
+	tournaments, err := loadTournaments()
+	if err != nil {
+		log.Error("failed to load tournaments", zap.Error(err))
+		return
+	}
+
+	for _, t := range tournaments {
+		if !t.Started() {
+			continue
+		}
+		br, results, err := tournamentBracket(t)
+		if err != nil {
+			log.Error("failed to load tournament results", zap.String("tournamentID", t.ID), zap.Error(err))
+			continue
+		}
+		if br.Finished() {
+			if _, announced := results[championKey]; !announced {
+				b.announceChampion(t, br)
+			}
+			continue
+		}
+
+		for _, m := range br.Due(now) {
+			for _, side := range []*tournament.Side{m.A, m.B} {
+				if side.Reported {
+					continue
+				}
+				if now.After(m.StartAt.Add(tournamentForfeit)) {
+					if ch, ok := b.channels[side.ChanID]; ok && side.GameID != 0 && ch.game.ID() == side.GameID {
+						// still playing the match
+						continue
+					}
+					log.Info("Tournament match forfeited", zap.String("tournamentID", t.ID), zap.String("matchID", m.ID), zap.String("chanID", side.ChanID))
+					b.reportMatch(t, m.ID, side.ChanID, 0)
+					continue
+				}
+				if side.GameID == 0 {
+					b.openMatchLobby(t, m, side)
+				}
+			}
+		}
+	}
+}
+
+// openMatchLobby opens the lobby of a match in the channel of side
+func (b *fam100Bot) openMatchLobby(t *tournament.Tournament, m *tournament.Match, side *tournament.Side) {
+	ch := b.openLobby(side.ChanID, side.Name)
+	if ch == nil {
+		// busy, try again on the next tick
+		return
+	}
+	ch.game.SetQuestionSequence(t.QuestionSeed(m.Round), 0)
+	if err := repo.DefaultDB.SetTournamentResult(t.ID, tournament.StartedKey(m.ID, side.ChanID), ch.game.ID()); err != nil {
+		log.Error("failed to start tournament match", zap.String("tournamentID", t.ID), zap.String("matchID", m.ID), zap.Error(err))
+	}
+	if err := repo.DefaultDB.SetChannelConfig(side.ChanID, matchConfig, t.ID+" "+m.ID); err != nil {
+		log.Error("failed to save channel match", zap.String("tournamentID", t.ID), zap.String("matchID", m.ID), zap.Error(err))
+	}
+	ch.saveLobby()
+	ch.startQuorumTimer(ch.quorumWait(), b.out)
+	tournamentMatchCount.Inc(1)
+
+	opponent := m.A
+	if opponent.ChanID == side.ChanID {
+		opponent = m.B
+	}
+	text := fmt.Sprintf(
+		fam100.T("<b>%s</b> babak %d: melawan <b>%s</b>\nKetik /join untuk ikut, butuh %d orang dalam %s. Score semua pemain dijumlahkan"),
+		escape(t.Name), m.Round, escape(opponent.Name), minQuorum, ch.quorumWait(),
+	)
+	b.out <- bot.Message{Chat: bot.Chat{ID: side.ChanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(ch.quorumWait())}
+	log.Info("Tournament match lobby", zap.String("tournamentID", t.ID), zap.String("matchID", m.ID), zap.String("chanID", side.ChanID))
+}
+
+// finishMatch reports the score of a finished game if it is a tournament match
+func (b *fam100Bot) finishMatch(ch *channel) {
+	v, err := repo.DefaultDB.ChannelConfig(ch.ID, matchConfig, "")
+	if err != nil {
+		log.Error("failed to load channel match", zap.String("chanID", ch.ID), zap.Error(err))
+		return
+	}
+	fields := strings.Fields(v)
+	if len(fields) != 2 {
+		return
+	}
+	tournamentID, matchID := fields[0], fields[1]
+	results, err := repo.DefaultDB.TournamentResults(tournamentID)
+	if err != nil {
+		log.Error("failed to load tournament results", zap.String("tournamentID", tournamentID), zap.Error(err))
+		return
+	}
+	if results[tournament.StartedKey(matchID, ch.ID)] != ch.game.ID() {
+		// not the game of the match
+		return
+	}
+	if _, reported := results[tournament.ScoreKey(matchID, ch.ID)]; reported {
+		return
+	}
+	t, err := loadTournament(tournamentID)
+	if err != nil {
+		log.Error("failed to load tournament", zap.String("tournamentID", tournamentID), zap.Error(err))
+		return
+	}
+
+	score := 0
+	for _, ps := range ch.game.Rank() {
+		score += ps.Score
+	}
+	b.reportMatch(t, matchID, ch.ID, score)
+	if err := repo.DefaultDB.SetChannelConfig(ch.ID, matchConfig, ""); err != nil {
+		log.Error("failed to clear channel match", zap.String("chanID", ch.ID), zap.Error(err))
+	}
+}
+
+// reportMatch saves the score of a channel and announces the result once the match is decided
+func (b *fam100Bot) reportMatch(t *tournament.Tournament, matchID, chanID string, score int) {
+	if err := repo.DefaultDB.SetTournamentResult(t.ID, tournament.ScoreKey(matchID, chanID), int64(score)); err != nil {
+		log.Error("failed to report tournament match", zap.String("tournamentID", t.ID), zap.String("matchID", matchID), zap.Error(err))
+		return
+	}
+	log.Info("Tournament match reported", zap.String("tournamentID", t.ID), zap.String("matchID", matchID), zap.String("chanID", chanID), zap.Int("score", score))
+
+	br, _, err := tournamentBracket(t)
+	if err != nil {
+		log.Error("failed to load tournament results", zap.String("tournamentID", t.ID), zap.Error(err))
+		return
+	}
+	for _, round := range br.Rounds {
+		for _, m := range round {
+			if m.ID != matchID || m.Winner == "" {
+				continue
+			}
+			winner := m.Side(m.Winner)
+			text := fmt.Sprintf(
+				fam100.T("<b>%s</b> babak %d\n%s %d - %d %s\n<b>%s</b> lolos ke babak berikutnya"),
+				escape(t.Name), m.Round, escape(m.A.Name), m.A.Score, m.B.Score, escape(m.B.Name), escape(winner.Name),
+			)
+			for _, s := range []*tournament.Side{m.A, m.B} {
+				b.out <- bot.Message{Chat: bot.Chat{ID: s.ChanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+			}
+		}
+	}
+}
+
+func (b *fam100Bot) announceChampion(t *tournament.Tournament, br *tournament.Bracket) {
+	if err := repo.DefaultDB.SetTournamentResult(t.ID, championKey, 1); err != nil {
+		log.Error("failed to save tournament champion", zap.String("tournamentID", t.ID), zap.Error(err))
+		return
+	}
+	text := fmt.Sprintf(fam100.T("🏆 <b>%s</b> juara <b>%s</b>!\n\n<pre>%s</pre>"), escape(br.Champion.Name), escape(t.Name), escape(br.String()))
+	for _, e := range t.Entrants {
+		b.out <- bot.Message{Chat: bot.Chat{ID: e.ChanID}, Text: text, Format: bot.HTML, DiscardAfter: time.Now().Add(10 * time.Minute)}
+	}
+	log.Info("Tournament finished", zap.String("tournamentID", t.ID), zap.String("champion", br.Champion.ChanID))
+}
+
+// tournamentText formats a tournament with its bracket
+func tournamentText(t *tournament.Tournament) string {
+	text := fmt.Sprintf("[%s] %s, %d channels", t.ID, t.Name, len(t.Entrants))
+	if !t.Started() {
+		for i, e := range t.Entrants {
+			text += fmt.Sprintf("\n%d. %s (%s)", i+1, e.Name, e.ChanID)
+		}
+		return text
+	}
+
+	loc := defaultLocation()
+	text += fmt.Sprintf(", start %s every %s", t.StartAt.In(loc).Format("2006-01-02 15:04 MST"), t.Interval)
+	br, _, err := tournamentBracket(t)
+	if err != nil {
+		return text + "\n" + err.Error()
+	}
+
+	return text + "\n" + br.String()
+}
+
+// cmdTournament handles admin commands of tournaments
+//
+//	/tournament [id]
+//	/tournament new [name]
+//	/tournament add|remove [id] [chanID]
+//	/tournament start [id] [YYYY-MM-DD HH:MM] [interval minutes]
+//	/tournament delete [id]
+func (b *fam100Bot) cmdTournament(msg *bot.Message) bool {
+	reply := func(text string) {
+		b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: text, Format: bot.Text}
+	}
+	usage := "usage:\n/tournament [id]\n/tournament new [name]\n/tournament add|remove [id] [chanID]\n/tournament start [id] [YYYY-MM-DD HH:MM] [interval minutes]\n/tournament delete [id]"
+	fields := strings.Fields(msg.Text)
+
+	if len(fields) < 2 {
+		tournaments, err := loadTournaments()
+		if err != nil {
+			reply("tournaments failed. " + err.Error())
+			return true
+		}
+		lines := []string{fmt.Sprintf("%d tournaments", len(tournaments))}
+		for _, t := range tournaments {
+			lines = append(lines, fmt.Sprintf("[%s] %s, %d channels, started: %t", t.ID, t.Name, len(t.Entrants), t.Started()))
+		}
+		reply(strings.Join(lines, "\n") + "\n\n" + usage)
+		return true
+	}
+
+	switch fields[1] {
+	case "new":
+		name := strings.Join(fields[2:], " ")
+		if name == "" {
+			reply(usage)
+			return true
+		}
+		t := tournament.New(strconv.FormatInt(time.Now().Unix(), 36), name, rand.Int63())
+		if err := saveTournament(t); err != nil {
+			reply("tournament failed. " + err.Error())
+			return true
+		}
+		reply(tournamentText(t))
+
+	case "add", "remove":
+		if len(fields) < 4 {
+			reply(usage)
+			return true
+		}
+		t, err := loadTournament(fields[2])
+		if err != nil {
+			reply(err.Error())
+			return true
+		}
+		chanID := fields[3]
+		if fields[1] == "add" {
+			channels, _ := repo.DefaultDB.Channels()
+			name := channels[chanID]
+			if name == "" {
+				name = chanID
+			}
+			err = t.Register(chanID, name)
+		} else {
+			err = t.Unregister(chanID)
+		}
+		if err == nil {
+			err = saveTournament(t)
+		}
+		if err != nil {
+			reply(err.Error())
+			return true
+		}
+		reply(tournamentText(t))
+
+	case "start":
+		if len(fields) < 6 {
+			reply(usage)
+			return true
+		}
+		t, err := loadTournament(fields[2])
+		if err != nil {
+			reply(err.Error())
+			return true
+		}
+		at, err := time.ParseInLocation("2006-01-02 15:04", fields[3]+" "+fields[4], defaultLocation())
+		if err != nil {
+			reply(err.Error())
+			return true
+		}
+		minutes, err := strconv.Atoi(fields[5])
+		if err != nil {
+			reply(err.Error())
+			return true
+		}
+		if err := t.Start(at, time.Duration(minutes)*time.Minute, tournamentForfeit); err != nil {
+			reply(err.Error())
+			return true
+		}
+		if err := saveTournament(t); err != nil {
+			reply("tournament failed. " + err.Error())
+			return true
+		}
+		reply(tournamentText(t))
+
+	case "delete":
+		if len(fields) < 3 {
+			reply(usage)
+			return true
+		}
+		if err := repo.DefaultDB.DeleteTournament(fields[2]); err != nil {
+			reply("tournament failed. " + err.Error())
+			return true
+		}
+		reply("deleted " + fields[2])
+
+	default:
+		t, err := loadTournament(fields[1])
+		if err != nil {
+			reply(err.Error())
+			return true
+		}
+		reply(tournamentText(t))
+	}
+
+	return true
+}
+
+// cmdBracket handles "/tournament" in a channel, shows the bracket of tournaments the channel plays in
+func (b *fam100Bot) cmdBracket(msg *bot.Message) bool {
+	defer cmdBracketTimer.UpdateSince(time.Now())
+
+	if rateLimited("tournament", msg.Chat.ID, cmdRateDelay) {
+		return true
+	}
+
+	commandBracketCount.Inc(1)
+	tournaments, err := loadTournaments()
+	if err != nil {
+		log.Error("failed to load tournaments", zap.Error(err))
+		return true
+	}
+
+	var texts []string
+	for _, t := range tournaments {
+		for _, e := range t.Entrants {
+			if e.ChanID == msg.Chat.ID {
+				texts = append(texts, "<pre>"+escape(tournamentText(t))+"</pre>")
+				break
+			}
+		}
+	}
+	if len(texts) == 0 {
+		texts = append(texts, fam100.T("Channel ini tidak ikut turnamen"))
+	}
+	b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: strings.Join(texts, "\n"), Format: bot.HTML, DiscardAfter: time.Now().Add(time.Minute)}
+
+	return true
+}
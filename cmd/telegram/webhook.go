@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/bot"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/repo"
+	"github.com/yulrizka/fam100/webhook"
+)
+
+// webhookLogSize is the number of delivery attempts kept in the delivery log
+const webhookLogSize = 1000
+
+// repoDeliveryLog stores webhook delivery attempts in the repo
+type repoDeliveryLog struct{}
+
+func (repoDeliveryLog) Record(d webhook.Delivery) error {
+	if !d.Success() {
+		log.Warn("webhook delivery failed", zap.String("url", d.URL), zap.String("event", d.Event), zap.Int("attempt", d.Attempt), zap.String("error", d.Error))
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return repo.DefaultDB.AddWebhookDelivery(data, webhookLogSize)
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// webhookEndpoints returns the global webhooks and the webhook configured for the channel
+func webhookEndpoints(chanID string) []webhook.Endpoint {
+	var endpoints []webhook.Endpoint
+	events := splitList(webhookEvents)
+	for _, url := range splitList(webhookURLs) {
+		endpoints = append(endpoints, webhook.Endpoint{URL: url, Secret: webhookSecret, Events: events})
+	}
+
+	url, err := repo.DefaultDB.ChannelConfig(chanID, "webhook", "")
+	if err != nil || url == "" {
+		return endpoints
+	}
+	secret, _ := repo.DefaultDB.ChannelConfig(chanID, "webhookSecret", "")
+	chanEvents, _ := repo.DefaultDB.ChannelConfig(chanID, "webhookEvents", "")
+	endpoint := webhook.Endpoint{URL: url, Secret: secret, Events: splitList(chanEvents)}
+	if err := endpoint.Validate(); err != nil {
+		log.Warn("invalid channel webhook", zap.String("chanID", chanID), zap.Error(err))
+		return endpoints
+	}
+
+	return append(endpoints, endpoint)
+}
+
+// webhookEvent maps game event to webhook event, empty if it is not sent to webhooks
+func webhookEvent(e fam100.Event) string {
+	switch msg := e.(type) {
+	case fam100.StateMessage:
+		if msg.State == fam100.Started {
+			return webhook.GameStarted
+		}
+	case fam100.RankMessage:
+		if msg.Final {
+			return webhook.GameFinished
+		}
+		return webhook.RoundFinished
+	case fam100.AnswerMessage:
+		return webhook.AnswerCorrect
+	}
+
+	return ""
+}
+
+// subscribe delivers the game events to the chat, the player progress and to the webhooks of the channel
+func (b *fam100Bot) subscribe(game *fam100.Game) {
+	// the chat skips the ticks and board updates it can't keep up with, webhooks are best effort
+	game.SubscribeChan("telegram", b.gameOut, fam100.Transient)
+	if game.Practice {
+		// practice is too easy to farm badges
+		return
+	}
+	game.SubscribeChan("progress", b.progressOut, nil)
+	if b.hooks != nil {
+		game.SubscribeBestEffort("webhook", b.webhookOut)
+	}
+}
+
+// handleWebhooks sends game events to the webhooks configured when the event happened
+func (b *fam100Bot) handleWebhooks() {
+	for {
+		select {
+		case <-b.quit:
+			return
+		case e := <-b.webhookOut:
+			event := webhookEvent(e)
+			if event == "" {
+				continue
+			}
+			chanID := e.EventChanID()
+			p := webhook.Payload{Event: event, GameID: e.EventGameID(), ChanID: chanID, At: time.Now(), Data: e}
+			for _, endpoint := range webhookEndpoints(chanID) {
+				if endpoint.Accept(event) && !b.hooks.Send(endpoint, p) {
+					log.Warn("webhook not queued", zap.String("chanID", chanID), zap.String("url", endpoint.URL), zap.String("event", event))
+				}
+			}
+		}
+	}
+}
+
+// cmdWebhooks shows the latest webhook deliveries
+func (b *fam100Bot) cmdWebhooks(msg *bot.Message) bool {
+	entries, err := repo.DefaultDB.WebhookDeliveries(20)
+	if err != nil {
+		b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: "webhooks failed. " + err.Error(), Format: bot.Text}
+		return true
+	}
+
+	lines := []string{fmt.Sprintf("last %d deliveries:", len(entries))}
+	for _, data := range entries {
+		var d webhook.Delivery
+		if err := json.Unmarshal(data, &d); err != nil {
+			continue
+		}
+		line := fmt.Sprintf("%s %s #%d %s %d", d.At.Format("01-02 15:04:05"), d.Event, d.Attempt, d.URL, d.Status)
+		if d.Error != "" {
+			line += " " + d.Error
+		}
+		lines = append(lines, line)
+	}
+	b.out <- bot.Message{Chat: bot.Chat{ID: msg.Chat.ID}, Text: strings.Join(lines, "\n"), Format: bot.Text}
+
+	return true
+}
@@ -0,0 +1,87 @@
+package fam100
+
+import (
+	"hash/crc32"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// DailyLayout is the format of the day of a daily challenge
+const DailyLayout = "2006-01-02"
+
+// Day returns the daily challenge day of t in loc
+func Day(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(DailyLayout)
+}
+
+// DailySeed returns the question seed of the daily challenge, every game of the same
+// day plays the same questions regardless of the channel
+func DailySeed(day string) int64 {
+	return int64(crc32.ChecksumIEEE([]byte("daily:" + day)))
+}
+
+// SetDaily makes the game the daily challenge of day. The score goes to the daily
+// ranking only and players that completed the challenge of the day can not answer.
+// It should be called before the game is started
+func (g *Game) SetDaily(day string) {
+	g.Daily = day
+	g.SetQuestionSequence(DailySeed(day), 0)
+}
+
+// dailyCompleted returns whether the player already completed the daily challenge of the game
+func (g *Game) dailyCompleted(playerID model.PlayerID) bool {
+	if done, ok := g.dailyDone[playerID]; ok {
+		return done
+	}
+	done, err := repo.DefaultDB.DailyCompleted(g.Daily, playerID)
+	if err != nil {
+		log.Error("failed to check daily challenge", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Error(err))
+		return false
+	}
+	if g.dailyDone == nil {
+		g.dailyDone = make(map[model.PlayerID]bool)
+	}
+	g.dailyDone[playerID] = done
+
+	return done
+}
+
+// participate records a player that played the game
+func (g *Game) participate(p model.Player) {
+	if g.participants == nil {
+		g.participants = make(map[model.PlayerID]model.Player)
+	}
+	g.participants[p.ID] = p
+}
+
+// saveDailyScore completes the daily challenge of each participant, also of those that
+// did not score. Only the first game of the day counts
+func (g *Game) saveDailyScore() {
+	if g.lostLease() {
+		return
+	}
+	scores := make([]model.PlayerScore, 0, len(g.participants))
+	scored := make(map[model.PlayerID]bool, len(g.rank))
+	for _, ps := range g.rank {
+		scores = append(scores, ps)
+		scored[ps.PlayerID] = true
+	}
+	for id, p := range g.participants {
+		if !scored[id] {
+			scores = append(scores, model.PlayerScore{PlayerID: id, Name: p.Name})
+		}
+	}
+	for _, ps := range scores {
+		first, err := repo.DefaultDB.SaveDailyScore(g.Daily, ps)
+		if err != nil {
+			log.Error("failed to save daily score", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Error(err))
+			continue
+		}
+		if !first {
+			log.Info("Daily challenge already completed", zap.String("chanID", g.ChanID), zap.String("playerID", string(ps.PlayerID)), zap.String("day", g.Daily))
+		}
+	}
+}
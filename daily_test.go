@@ -0,0 +1,75 @@
+package fam100
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
+)
+
+func TestDailySeed(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*3600)
+	at := time.Date(2017, 3, 1, 20, 0, 0, 0, time.UTC)
+	if want, got := "2017-03-02", Day(at, jakarta); want != got {
+		t.Errorf("day, want %s got %s", want, got)
+	}
+	if DailySeed("2017-03-01") != DailySeed("2017-03-01") {
+		t.Errorf("seed of the same day should be the same")
+	}
+	if DailySeed("2017-03-01") == DailySeed("2017-03-02") {
+		t.Errorf("seed of different days should be different")
+	}
+}
+
+func TestDailyCompleted(t *testing.T) {
+	oDB := repo.DefaultDB
+	defer func() { repo.DefaultDB = oDB }()
+	repo.DefaultDB = new(repo.MemoryDB)
+
+	day := "2017-03-01"
+	done := model.PlayerScore{PlayerID: "1", Name: "done", Score: 10}
+	if first, err := repo.DefaultDB.SaveDailyScore(day, done); err != nil || !first {
+		t.Fatalf("first daily score should be saved, got %t %v", first, err)
+	}
+
+	q := testQuestion(t, "1")
+	g := &Game{
+		ChanID:        "1",
+		scoring:       RawScore{},
+		firstAnswerAt: make(map[model.PlayerID]time.Time),
+		lastAnswerAt:  make(map[model.PlayerID]time.Time),
+		answerCount:   make(map[model.PlayerID]int),
+	}
+	g.SetDaily(day)
+	if want, got := DailySeed(day), g.seed; want != got {
+		t.Errorf("seed, want %d got %d", want, got)
+	}
+
+	r, err := newRound(q, make(map[model.PlayerID]model.Player))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.state = RoundStarted
+
+	g.handleMessage(TextMessage{Player: model.Player{ID: "1", Name: "done"}, Text: "gemes", ReceivedAt: r.startedAt.Add(5 * time.Second)}, r)
+	if r.correct[0] != "" {
+		t.Errorf("player that completed the daily challenge should not answer")
+	}
+	g.handleMessage(TextMessage{Player: model.Player{ID: "2", Name: "new"}, Text: "gemes", ReceivedAt: r.startedAt.Add(5 * time.Second)}, r)
+	if want, got := model.PlayerID("2"), r.correct[0]; want != got {
+		t.Errorf("correct, want %s got %s", want, got)
+	}
+
+	// joined without answering
+	g.JoinTeam(model.Player{ID: "3", Name: "quiet"}, "")
+
+	g.rank = model.Rank{{PlayerID: "2", Name: "new", Score: 33}}
+	g.saveDailyScore()
+	if first, _ := repo.DefaultDB.SaveDailyScore(day, g.rank[0]); first {
+		t.Errorf("daily challenge should be completed once per day")
+	}
+	if done, _ := repo.DefaultDB.DailyCompleted(day, "3"); !done {
+		t.Errorf("participant without score should complete the daily challenge")
+	}
+}
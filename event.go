@@ -0,0 +1,281 @@
+package fam100
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/uber-go/zap"
+)
+
+// EventKind is the type of game event
+type EventKind string
+
+// Available EventKind
+const (
+	EventText        EventKind = "text"
+	EventState       EventKind = "state"
+	EventBoard       EventKind = "board"
+	EventRank        EventKind = "rank"
+	EventTick        EventKind = "tick"
+	EventWrongAnswer EventKind = "wrongAnswer"
+	EventSkipVote    EventKind = "skipVote"
+	EventSuddenDeath EventKind = "suddenDeath"
+	EventStrike      EventKind = "strike"
+	EventSteal       EventKind = "steal"
+	EventAnswer      EventKind = "answer"
+)
+
+// Event is a message published by a game to its subscribers
+type Event interface {
+	EventKind() EventKind
+	EventGameID() int64
+	EventChanID() string
+}
+
+func (m TextMessage) EventKind() EventKind        { return EventText }
+func (m StateMessage) EventKind() EventKind       { return EventState }
+func (m QNAMessage) EventKind() EventKind         { return EventBoard }
+func (m RankMessage) EventKind() EventKind        { return EventRank }
+func (m TickMessage) EventKind() EventKind        { return EventTick }
+func (m WrongAnswerMessage) EventKind() EventKind { return EventWrongAnswer }
+func (m SkipVoteMessage) EventKind() EventKind    { return EventSkipVote }
+func (m SuddenDeathMessage) EventKind() EventKind { return EventSuddenDeath }
+func (m StrikeMessage) EventKind() EventKind      { return EventStrike }
+func (m StealMessage) EventKind() EventKind       { return EventSteal }
+func (m AnswerMessage) EventKind() EventKind      { return EventAnswer }
+
+func (m TextMessage) EventGameID() int64        { return m.GameID }
+func (m StateMessage) EventGameID() int64       { return m.GameID }
+func (m QNAMessage) EventGameID() int64         { return m.GameID }
+func (m RankMessage) EventGameID() int64        { return m.GameID }
+func (m TickMessage) EventGameID() int64        { return m.GameID }
+func (m WrongAnswerMessage) EventGameID() int64 { return m.GameID }
+func (m SkipVoteMessage) EventGameID() int64    { return m.GameID }
+func (m SuddenDeathMessage) EventGameID() int64 { return m.GameID }
+func (m StrikeMessage) EventGameID() int64      { return m.GameID }
+func (m StealMessage) EventGameID() int64       { return m.GameID }
+func (m AnswerMessage) EventGameID() int64      { return m.GameID }
+
+func (m TextMessage) EventChanID() string        { return m.ChanID }
+func (m StateMessage) EventChanID() string       { return m.ChanID }
+func (m QNAMessage) EventChanID() string         { return m.ChanID }
+func (m RankMessage) EventChanID() string        { return m.ChanID }
+func (m TickMessage) EventChanID() string        { return m.ChanID }
+func (m WrongAnswerMessage) EventChanID() string { return m.ChanID }
+func (m SkipVoteMessage) EventChanID() string    { return m.ChanID }
+func (m SuddenDeathMessage) EventChanID() string { return m.ChanID }
+func (m StrikeMessage) EventChanID() string      { return m.ChanID }
+func (m StealMessage) EventChanID() string       { return m.ChanID }
+func (m AnswerMessage) EventChanID() string      { return m.ChanID }
+
+// SubscriptionQueueSize is the number of events queued for a subscription of a shared channel
+var SubscriptionQueueSize = 1000
+
+// Subscription delivers events of a game to one consumer. The game never waits for
+// a consumer, every subscription has its own bounded queue and an event that does
+// not fit in the queue is dropped
+type Subscription struct {
+	Name string
+	C    <-chan Event
+
+	queue   chan Event       // events waiting for the consumer
+	out     chan Event       // shared channel fed from the queue, nil if C is the queue
+	lossy   func(Event) bool // events that are dropped when out is full
+	fn      func(Event)
+	dropped int64
+
+	start     sync.Once
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Dropped returns the number of events that did not reach the consumer
+func (s *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+func (s *Subscription) deliver(e Event) bool {
+	if s.fn != nil {
+		s.fn(e)
+		return true
+	}
+	if s.out != nil {
+		s.start.Do(func() { go s.forward() })
+	}
+	select {
+	case s.queue <- e:
+		return true
+	default:
+		s.drop()
+		return false
+	}
+}
+
+// forward moves the queued events to the shared channel. After the subscription is
+// closed the queue is still emptied so the last events of a game are not lost
+func (s *Subscription) forward() {
+	for {
+		select {
+		case e := <-s.queue:
+			s.send(e)
+		case <-s.done:
+			for {
+				select {
+				case e := <-s.queue:
+					s.send(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send waits for the consumer of the shared channel unless the event is lossy
+func (s *Subscription) send(e Event) {
+	if s.lossy == nil || !s.lossy(e) {
+		s.out <- e
+		return
+	}
+	select {
+	case s.out <- e:
+	default:
+		s.drop()
+	}
+}
+
+func (s *Subscription) drop() {
+	atomic.AddInt64(&s.dropped, 1)
+	eventDroppedCount.Inc(1)
+	metrics.GetOrRegisterCounter("game.event.dropped."+s.Name, metrics.DefaultRegistry).Inc(1)
+}
+
+// close stops forwarding once the queued events are delivered
+func (s *Subscription) close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// Subscribe returns a subscription with a buffer of the given size
+func (g *Game) Subscribe(name string, buffer int) *Subscription {
+	c := make(chan Event, buffer)
+	s := &Subscription{Name: name, C: c, queue: c, done: make(chan struct{})}
+	g.subscribe(s)
+
+	return s
+}
+
+// SubscribeChan delivers events to c, the same channel can be shared by subscriptions
+// of many games. The events wait in the queue of the subscription so a slow consumer
+// of c never blocks the game. Events for which lossy returns true are dropped instead
+// of waiting when c is full, lossy can be nil
+func (g *Game) SubscribeChan(name string, c chan Event, lossy func(Event) bool) *Subscription {
+	s := &Subscription{
+		Name:  name,
+		C:     c,
+		queue: make(chan Event, SubscriptionQueueSize),
+		out:   c,
+		lossy: lossy,
+		done:  make(chan struct{}),
+	}
+	g.subscribe(s)
+
+	return s
+}
+
+// SubscribeBestEffort delivers events to c and drops them when c is full
+func (g *Game) SubscribeBestEffort(name string, c chan Event) *Subscription {
+	return g.SubscribeChan(name, c, func(Event) bool { return true })
+}
+
+// SubscribeFunc calls fn from the game loop for every event, fn must not block
+func (g *Game) SubscribeFunc(name string, fn func(Event)) *Subscription {
+	s := &Subscription{Name: name, fn: fn, done: make(chan struct{})}
+	g.subscribe(s)
+
+	return s
+}
+
+// Transient returns whether the event is only worth showing right away, such as the
+// ticks and the board updates during a round. The board revealed at the end of the
+// round is not transient
+func Transient(e Event) bool {
+	switch msg := e.(type) {
+	case TickMessage, WrongAnswerMessage:
+		return true
+	case QNAMessage:
+		return !msg.ShowUnanswered
+	}
+
+	return false
+}
+
+// Unsubscribe stops delivering events to s
+func (g *Game) Unsubscribe(s *Subscription) {
+	g.subMu.Lock()
+	defer g.subMu.Unlock()
+
+	subscribers := make([]*Subscription, 0, len(g.subscribers))
+	for _, sub := range g.subscribers {
+		if sub != s {
+			subscribers = append(subscribers, sub)
+		}
+	}
+	g.subscribers = subscribers
+	s.close()
+}
+
+// closeSubscriptions stops forwarding events when the game stops playing
+func (g *Game) closeSubscriptions() {
+	g.subMu.Lock()
+	subscribers := g.subscribers
+	g.subMu.Unlock()
+
+	for _, s := range subscribers {
+		s.close()
+	}
+}
+
+func (g *Game) subscribe(s *Subscription) {
+	g.subMu.Lock()
+	defer g.subMu.Unlock()
+
+	// copy on write, publish reads the slice without holding the lock
+	subscribers := make([]*Subscription, len(g.subscribers), len(g.subscribers)+1)
+	copy(subscribers, g.subscribers)
+	g.subscribers = append(subscribers, s)
+}
+
+// publish delivers the event to all subscribers
+func (g *Game) publish(e Event) {
+	if g.stale {
+		return
+	}
+	g.subMu.Lock()
+	subscribers := g.subscribers
+	g.subMu.Unlock()
+
+	for _, s := range subscribers {
+		if !s.deliver(e) {
+			log.Debug("event dropped", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.String("subscriber", s.Name), zap.String("kind", string(e.EventKind())))
+		}
+	}
+}
+
+// countEvent is the subscriber that tracks published events
+func countEvent(e Event) {
+	metrics.GetOrRegisterCounter("game.event."+string(e.EventKind())+".count", metrics.DefaultRegistry).Inc(1)
+}
+
+// subscribeDefaults registers the built-in subscribers
+func (g *Game) subscribeDefaults() {
+	g.SubscribeFunc("metrics", countEvent)
+
+	if JournalDir == "" {
+		return
+	}
+	// the journal is opened when the game starts playing
+	g.SubscribeFunc("journal", func(e Event) {
+		g.record(journalKind(e), e)
+	})
+}
@@ -0,0 +1,117 @@
+package fam100
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscription(t *testing.T) {
+	g := &Game{id: 1, ChanID: "1"}
+	slow := g.Subscribe("slow", 1)
+	fast := g.Subscribe("fast", 10)
+	var kinds []EventKind
+	g.SubscribeFunc("func", func(e Event) { kinds = append(kinds, e.EventKind()) })
+
+	g.publish(StateMessage{GameID: g.id, ChanID: g.ChanID, State: Started})
+	g.publish(TickMessage{GameID: g.id, ChanID: g.ChanID})
+
+	// full buffer drops the event without blocking other subscribers
+	if want, got := int64(1), slow.Dropped(); want != got {
+		t.Errorf("slow dropped want %d got %d", want, got)
+	}
+	if want, got := 2, len(fast.C); want != got {
+		t.Errorf("fast buffered want %d got %d", want, got)
+	}
+	if want, got := 2, len(kinds); want != got {
+		t.Fatalf("func events want %d got %d", want, got)
+	}
+	if want, got := EventTick, kinds[1]; want != got {
+		t.Errorf("kind want %s got %s", want, got)
+	}
+	e := <-fast.C
+	if want, got := int64(1), e.EventGameID(); want != got {
+		t.Errorf("gameID want %d got %d", want, got)
+	}
+
+	g.Unsubscribe(fast)
+	g.publish(TickMessage{GameID: g.id, ChanID: g.ChanID})
+	if want, got := 1, len(fast.C); want != got {
+		t.Errorf("unsubscribed should not receive event, buffered want %d got %d", want, got)
+	}
+}
+
+func TestSubscriptionChan(t *testing.T) {
+	oSize := SubscriptionQueueSize
+	defer func() { SubscriptionQueueSize = oSize }()
+	SubscriptionQueueSize = 2
+
+	g := &Game{id: 1, ChanID: "1"}
+	c := make(chan Event)
+	sub := g.SubscribeChan("shared", c, nil)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 1; i <= 10; i++ {
+			g.publish(RankMessage{GameID: g.id, ChanID: g.ChanID, Round: i})
+		}
+		close(done)
+	}()
+	// nobody reads the shared channel, the game still continues
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish should not wait for the consumer")
+	}
+	// the queue and the event being forwarded are kept
+	if dropped := sub.Dropped(); dropped < 7 {
+		t.Errorf("dropped want at least 7 got %d", dropped)
+	}
+
+	// closing still forwards the queued events in order
+	g.closeSubscriptions()
+	var rounds []int
+	for {
+		select {
+		case e := <-c:
+			rounds = append(rounds, e.(RankMessage).Round)
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		break
+	}
+	if want, got := 10-int(sub.Dropped()), len(rounds); want != got {
+		t.Fatalf("forwarded want %d got %d", want, got)
+	}
+	for i := 1; i < len(rounds); i++ {
+		if rounds[i] <= rounds[i-1] {
+			t.Errorf("events out of order %v", rounds)
+		}
+	}
+}
+
+func TestSubscriptionTransient(t *testing.T) {
+	g := &Game{id: 1, ChanID: "1"}
+	c := make(chan Event, 1)
+	c <- TextMessage{GameID: g.id, ChanID: g.ChanID, Text: "busy"}
+	sub := g.SubscribeChan("chat", c, Transient)
+
+	// a tick is dropped when the consumer is busy
+	g.publish(TickMessage{GameID: g.id, ChanID: g.ChanID})
+	deadline := time.Now().Add(time.Second)
+	for sub.Dropped() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if want, got := int64(1), sub.Dropped(); want != got {
+		t.Fatalf("dropped want %d got %d", want, got)
+	}
+
+	// the rank waits for the consumer
+	g.publish(RankMessage{GameID: g.id, ChanID: g.ChanID, Final: true})
+	if want, got := EventText, (<-c).EventKind(); want != got {
+		t.Errorf("kind want %s got %s", want, got)
+	}
+	if want, got := EventRank, (<-c).EventKind(); want != got {
+		t.Errorf("kind want %s got %s", want, got)
+	}
+	g.closeSubscriptions()
+}
@@ -0,0 +1,87 @@
+package fam100
+
+import (
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
+)
+
+// Flood protection configuration, AnswerBurst of 0 disables the rate limit
+var (
+	AnswerBurst   = 5                      // answers a player can send at once
+	AnswerRefill  = 500 * time.Millisecond // time to regain one answer
+	MinAnswerTime = time.Second            // correct answer faster than this after the question is shown is flagged
+)
+
+// Reason of flagging a player
+const (
+	FlagFlood = "flood"
+	FlagFast  = "fast"
+)
+
+// answerLimiter is a token bucket per player. Time is taken from the message
+// so the result is the same when the game is replayed
+type answerLimiter struct {
+	burst   int
+	refill  time.Duration
+	buckets map[model.PlayerID]*answerBucket
+}
+
+type answerBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newAnswerLimiter(burst int, refill time.Duration) *answerLimiter {
+	if burst <= 0 || refill <= 0 {
+		return nil
+	}
+	return &answerLimiter{burst: burst, refill: refill, buckets: make(map[model.PlayerID]*answerBucket)}
+}
+
+// allow takes one token from the player's bucket, nil limiter allows everything
+func (l *answerLimiter) allow(p model.PlayerID, at time.Time) bool {
+	if l == nil {
+		return true
+	}
+	b, ok := l.buckets[p]
+	if !ok {
+		b = &answerBucket{tokens: float64(l.burst), last: at}
+		l.buckets[p] = b
+	}
+	if elapsed := at.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(l.refill)
+		if b.tokens > float64(l.burst) {
+			b.tokens = float64(l.burst)
+		}
+		b.last = at
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
+
+// flag records suspicious behaviour of a player, only once per round and reason
+func (g *Game) flag(r *round, p model.Player, reason string) {
+	key := string(p.ID) + ":" + reason
+	if r.flagged[key] {
+		return
+	}
+	r.flagged[key] = true
+	playerFlaggedCount.Inc(1)
+	log.Warn("player flagged",
+		zap.String("chanID", g.ChanID),
+		zap.Int64("gameID", g.id),
+		zap.Int64("roundID", r.id),
+		zap.String("playerID", string(p.ID)),
+		zap.String("playerName", p.Name),
+		zap.String("reason", reason))
+	if err := repo.DefaultDB.FlagPlayer(g.ChanID, p.ID, reason); err != nil {
+		log.Error("failed to flag player", zap.String("chanID", g.ChanID), zap.String("playerID", string(p.ID)), zap.Error(err))
+	}
+}
@@ -0,0 +1,83 @@
+package fam100
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
+)
+
+func TestAnswerLimiter(t *testing.T) {
+	l := newAnswerLimiter(2, time.Second)
+	now := time.Now()
+	if !l.allow("1", now) || !l.allow("1", now) {
+		t.Fatal("burst should be allowed")
+	}
+	if l.allow("1", now.Add(500*time.Millisecond)) {
+		t.Errorf("bucket should be empty")
+	}
+	if !l.allow("2", now) {
+		t.Errorf("other player has its own bucket")
+	}
+	if !l.allow("1", now.Add(1100*time.Millisecond)) {
+		t.Errorf("one answer should be refilled")
+	}
+	if l.allow("1", now.Add(1200*time.Millisecond)) {
+		t.Errorf("bucket should be empty after refill is used")
+	}
+
+	disabled := newAnswerLimiter(0, time.Second)
+	if !disabled.allow("1", now) {
+		t.Errorf("disabled limiter should allow everything")
+	}
+}
+
+func TestFloodProtection(t *testing.T) {
+	oDB := repo.DefaultDB
+	defer func() { repo.DefaultDB = oDB }()
+	repo.DefaultDB = new(repo.MemoryDB)
+
+	// apa yang orang katakan sambil memegang pipi orang lain*33:gemes*20:lucu*12:sayang*...
+	q := testQuestion(t, "1")
+	g := &Game{
+		ChanID:        "1",
+		scoring:       RawScore{},
+		firstAnswerAt: make(map[model.PlayerID]time.Time),
+		lastAnswerAt:  make(map[model.PlayerID]time.Time),
+		answerCount:   make(map[model.PlayerID]int),
+		limiter:       newAnswerLimiter(1, 200*time.Millisecond),
+	}
+	r, err := newRound(q, make(map[model.PlayerID]model.Player))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.state = RoundStarted
+
+	bot := model.Player{ID: "1", Name: "bot"}
+	at := r.startedAt.Add(100 * time.Millisecond)
+	if handled := g.handleMessage(TextMessage{Player: bot, Text: "salah", ReceivedAt: at}, r); !handled {
+		t.Fatal("wrong answer should be handled")
+	}
+	// throttled answer is dropped even though it is correct
+	g.handleMessage(TextMessage{Player: bot, Text: "gemes", ReceivedAt: at}, r)
+	if r.correct[0] != "" {
+		t.Errorf("throttled answer should not be accepted")
+	}
+	if !r.flagged["1:"+FlagFlood] {
+		t.Errorf("player should be flagged for flooding")
+	}
+
+	if handled := g.handleMessage(TextMessage{Player: bot, Text: "gemes", ReceivedAt: at.Add(300 * time.Millisecond)}, r); handled {
+		t.Fatal("correct answer should not be handled")
+	}
+	if !r.flagged["1:"+FlagFast] {
+		t.Errorf("player should be flagged for answering too fast")
+	}
+
+	human := model.Player{ID: "2", Name: "human"}
+	g.handleMessage(TextMessage{Player: human, Text: "lucu", ReceivedAt: r.startedAt.Add(5 * time.Second)}, r)
+	if r.flagged["2:"+FlagFast] {
+		t.Errorf("human should not be flagged")
+	}
+}
@@ -2,7 +2,10 @@ package fam100
 
 import (
 	"math/rand"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -26,6 +29,35 @@ var (
 	playerActiveMap = cache.New(5*time.Minute, 30*time.Second)
 )
 
+// Team mode configuration
+var (
+	// StealEnabled gives the next team one guess to take the round points
+	// when the playing team fails to reveal the whole board
+	StealEnabled  = false
+	StealDuration = 20 * time.Second
+	MaxTeamName   = 20
+)
+
+// Skip configuration
+var (
+	// SkipPercent is the percentage of active players in the round that needs to vote /skip
+	SkipPercent = 50
+	// MaxSkipPerGame limits the number of question that can be replaced in a game
+	MaxSkipPerGame = 3
+)
+
+// SuddenDeathEnabled plays an extra round between tied players when the game ends with a tie for first place
+var SuddenDeathEnabled = false
+
+// Strike configuration, 0 disables the limit
+var (
+	// StrikeLimit is the number of wrong answers a player can make in a round
+	// before they are locked out until the next round
+	StrikeLimit = 0
+	// BoardStrikeLimit is the number of wrong answers in a round before the board is revealed
+	BoardStrikeLimit = 0
+)
+
 func init() {
 	log = zap.New(zap.NewJSONEncoder())
 	go func() {
@@ -44,6 +76,7 @@ type Message interface{}
 
 // TextMessage represents a chat message
 type TextMessage struct {
+	GameID     int64
 	ChanID     string
 	Player     model.Player
 	Text       string
@@ -57,18 +90,78 @@ type StateMessage struct {
 	Round     int
 	State     State
 	RoundText QNAMessage //question and answer
+	Team      string     // team mode, team that is playing or stealing
 }
 
 // TickMessage represents time left notification
 type TickMessage struct {
+	GameID   int64
 	ChanID   string
 	TimeLeft time.Duration
 }
 
 type WrongAnswerMessage TickMessage
 
+// SkipVoteMessage represents a player vote to skip current question
+type SkipVoteMessage struct {
+	GameID     int64
+	ChanID     string
+	Round      int
+	Player     model.Player
+	Votes      int
+	Needed     int
+	ReceivedAt time.Time
+}
+
+// SuddenDeathMessage represents start and end of a sudden death round
+type SuddenDeathMessage struct {
+	GameID   int64
+	ChanID   string
+	Players  model.Rank
+	Finished bool
+	Winner   model.PlayerScore // empty if nobody answered correctly
+}
+
+// StrikeMessage represents a wrong answer when strike mode is enabled
+type StrikeMessage struct {
+	GameID           int64
+	ChanID           string
+	Round            int
+	Player           model.Player
+	Strikes          int  // player strikes in the round
+	Locked           bool // player can not answer until the next round
+	BoardStrikes     int
+	BoardStrikeLimit int
+}
+
+// AnswerMessage represents a correct answer of a player
+type AnswerMessage struct {
+	GameID     int64
+	ChanID     string
+	Round      int
+	Player     model.Player
+	Answer     string
+	Score      int
+	Top        bool          // the answer has the highest score of the board
+	Board      int           // number of answers on the board
+	Answered   int           // answers of the board answered by the player
+	Elapsed    time.Duration // time since the question was shown
+	ReceivedAt time.Time
+}
+
+// StealMessage represents the result of a steal attempt in team mode
+type StealMessage struct {
+	GameID  int64
+	ChanID  string
+	Round   int
+	Team    string
+	Player  model.Player
+	Success bool
+}
+
 // QNAMessage represents question and answer for a round
 type QNAMessage struct {
+	GameID         int64
 	ChanID         string
 	Round          int
 	QuestionText   string
@@ -76,6 +169,8 @@ type QNAMessage struct {
 	Answers        []roundAnswers
 	ShowUnanswered bool // reveal un-answered question (end of round)
 	TimeLeft       time.Duration
+	Strikes        int // wrong answers in this round
+	StrikeLimit    int // wrong answers allowed before the board is revealed, 0 is unlimited
 }
 
 type roundAnswers struct {
@@ -84,12 +179,35 @@ type roundAnswers struct {
 	Answered   bool
 	PlayerName string
 	Highlight  bool
+	Hint       string // masked answer text, empty if no hint is shown
+	HintLevel  int
 }
 type RankMessage struct {
-	ChanID string
-	Round  int
-	Rank   model.Rank
-	Final  bool
+	GameID   int64
+	ChanID   string
+	Round    int
+	Rank     model.Rank
+	TeamRank model.TeamRank // empty if not in team mode
+	Final    bool
+
+	// Breakdown explains the points of each correct answer in the round
+	Breakdown []ScoreDetail
+	Recap     RoundRecap
+}
+
+// RoundRecap summarizes the last finished round
+type RoundRecap struct {
+	MVP        model.PlayerScore `json:"mvp"`        // most points in the round, empty if nobody answered
+	Fastest    FastestAnswer     `json:"fastest"`    // empty if nobody answered
+	Unanswered []string          `json:"unanswered"` // answers nobody found
+	Guesses    int               `json:"guesses"`    // answers sent by players during the round
+}
+
+// FastestAnswer is the first correct answer of a round
+type FastestAnswer struct {
+	Player  model.Player  `json:"player"`
+	Answer  string        `json:"answer"`
+	Elapsed time.Duration `json:"elapsed"` // time since the question was shown
 }
 
 // State represents state of the round
@@ -101,7 +219,9 @@ const (
 	Started       State = "started"
 	Finished      State = "finished"
 	RoundStarted  State = "roundStarted"
+	RoundSteal    State = "roundSteal"
 	RoundTimeout  State = "RoundTimeout"
+	RoundSkipped  State = "roundSkipped"
 	RoundFinished State = "roundFinished"
 )
 
@@ -119,35 +239,182 @@ type Game struct {
 	currentRound     *round
 	questionDB       qna.Provider
 
-	In  chan Message
-	Out chan Message
+	// team mode
+	teams     map[model.PlayerID]string
+	teamNames []string
+	teamRank  model.TeamRank
+	steal     bool
+
+	strikeLimit      int
+	boardStrikeLimit int
+
+	scoring      ScoreStrategy
+	scoringName  string
+	roundDetails []ScoreDetail
+	roundRecap   RoundRecap
+	hints        bool
+	skipped      int
+	skipPercent  int
+	answerBurst  int
+	answerRefill time.Duration
+	limiter      *answerLimiter
+
+	// lastAnswerAt is the time of the latest correct answer of the players
+	lastAnswerAt map[model.PlayerID]time.Time
+
+	// tie breaker
+	firstAnswerAt     map[model.PlayerID]time.Time
+	answerCount       map[model.PlayerID]int
+	suddenDeath       map[model.PlayerID]bool
+	suddenDeathWinner model.PlayerID
+
+	journal Journal
+	resume  *Checkpoint
+
+	// finalizing is set after the last round, until the final rank is published
+	finalizing bool
+
+	// Fence is the lease token of the instance that runs the game, 0 disables fencing.
+	// The game stops writing once a newer lease of the channel was granted
+	Fence int64
+	stale bool
+
+	// Practice game is played alone, the score goes to the practice ranking only
+	Practice bool
+
+	// fixedSequence ignores the question limit of the channel so that games with the
+	// same sequence play the same questions
+	fixedSequence bool
+
+	// Daily is the day of the daily challenge, empty for other games
+	Daily     string
+	dailyDone map[model.PlayerID]bool
+
+	// participants joined or tried to answer, with or without a score
+	participants map[model.PlayerID]model.Player
+
+	In chan Message
+
+	subMu       sync.Mutex
+	subscribers []*Subscription
 }
 
 // NewGame create a new round
-func NewGame(chanID, chanName string, in, out chan Message, questionDB qna.Provider) (r *Game, err error) {
+func NewGame(chanID, chanName string, in chan Message, questionDB qna.Provider) (r *Game, err error) {
+	return NewGameWithID(int64(rand.Int31()), chanID, chanName, in, questionDB)
+}
 
+// NewGameWithID creates a game that keeps the id of a game created before, such as a
+// lobby that is resumed after a restart
+func NewGameWithID(id int64, chanID, chanName string, in chan Message, questionDB qna.Provider) (r *Game, err error) {
 	seed, totalRoundPlayed, err := repo.DefaultDB.NextGame(chanID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Game{
-		id:               int64(rand.Int31()),
+	g := &Game{
+		id:               id,
 		ChanID:           chanID,
 		chanName:         chanName,
 		State:            Created,
 		players:          make(map[model.PlayerID]model.Player),
+		teams:            make(map[model.PlayerID]string),
+		firstAnswerAt:    make(map[model.PlayerID]time.Time),
+		lastAnswerAt:     make(map[model.PlayerID]time.Time),
+		answerCount:      make(map[model.PlayerID]int),
 		seed:             seed,
 		totalRoundPlayed: totalRoundPlayed,
 		In:               in,
-		Out:              out,
 		questionDB:       questionDB,
-	}, err
+	}
+	g.subscribeDefaults()
+
+	return g, nil
+}
+
+// JoinTeam assigns the player to a team. It should be called before the game is started.
+// Game will be played in team mode if there are at least 2 teams
+func (g *Game) JoinTeam(p model.Player, team string) {
+	g.participate(p)
+	team = NormalizeTeam(team)
+	if team == "" {
+		return
+	}
+	g.players[p.ID] = p
+	g.teams[p.ID] = team
+}
+
+// SetQuestionSequence makes the game play questions of another sequence, games with
+// the same seed and played count get the same questions. It should be called before the game is started
+func (g *Game) SetQuestionSequence(seed int64, played int) {
+	g.seed = seed
+	g.totalRoundPlayed = played
+	g.fixedSequence = true
+}
+
+// QuestionSequence returns the sequence set with SetQuestionSequence, fixed is false
+// if the game plays the sequence of the channel
+func (g *Game) QuestionSequence() (seed int64, played int, fixed bool) {
+	if !g.fixedSequence {
+		return 0, 0, false
+	}
+	return g.seed, g.totalRoundPlayed, true
+}
+
+// ID returns the id of the game
+func (g *Game) ID() int64 {
+	return g.id
+}
+
+// Rank returns the score of the game so far
+func (g *Game) Rank() model.Rank {
+	rank := make(model.Rank, len(g.rank))
+	copy(rank, g.rank)
+	return rank
+}
+
+// Answerers returns players whose latest correct answer is not before since.
+// It should only be called after the game is finished
+func (g *Game) Answerers(since time.Time) []model.Player {
+	var players []model.Player
+	for _, ps := range g.rank {
+		if at, ok := g.lastAnswerAt[ps.PlayerID]; ok && !at.Before(since) {
+			players = append(players, model.Player{ID: ps.PlayerID, Name: ps.Name})
+		}
+	}
+	return players
+}
+
+// NormalizeTeam returns the team name as it is used in the game
+func NormalizeTeam(team string) string {
+	team = strings.ToLower(strings.TrimSpace(team))
+	// truncate by runes so a multi byte character is not split
+	if runes := []rune(team); len(runes) > MaxTeamName {
+		team = string(runes[:MaxTeamName])
+	}
+
+	return team
 }
 
 // Start the game
 func (g *Game) Start() {
 	g.State = Started
+	g.setup()
+	g.openJournal()
+	g.record(JournalGame, JournalGameData{
+		ChanID:           g.ChanID,
+		ChanName:         g.chanName,
+		Seed:             g.seed,
+		Teams:            g.teams,
+		Steal:            g.steal,
+		StrikeLimit:      g.strikeLimit,
+		BoardStrikeLimit: g.boardStrikeLimit,
+		Scoring:          g.scoringName,
+		Hints:            g.hints,
+		SkipPercent:      g.skipPercent,
+		AnswerBurst:      g.answerBurst,
+		AnswerRefill:     g.answerRefill,
+	})
 	log.Info("Game started",
 		zap.String("chanID", g.ChanID),
 		zap.Int64("gameID", g.id),
@@ -155,130 +422,561 @@ func (g *Game) Start() {
 		zap.Int("totalRoundPlayed", g.totalRoundPlayed))
 
 	go func() {
-		g.Out <- StateMessage{ChanID: g.ChanID, State: Started, GameID: g.id}
-		for i := 1; i <= RoundPerGame; i++ {
-			err := g.startRound(i)
-			if err != nil {
-				log.Error("starting round failed", zap.String("chanID", g.ChanID), zap.Error(err))
-			}
-			final := i == RoundPerGame
-			g.Out <- RankMessage{ChanID: g.ChanID, Round: i, Rank: g.rank, Final: final}
-			if !final {
-				time.Sleep(DelayBetweenRound)
+		// play returns early when the lease is lost
+		defer g.closeJournal()
+		defer g.closeSubscriptions()
+		g.publish(StateMessage{GameID: g.id, ChanID: g.ChanID, State: Started})
+		g.play(1)
+	}()
+}
+
+// setup reads the game configuration of the channel
+func (g *Game) setup() {
+	g.setupTeams()
+	g.strikeLimit = g.configInt("strikeLimit", StrikeLimit)
+	g.boardStrikeLimit = g.configInt("boardStrikeLimit", BoardStrikeLimit)
+	g.scoringName, _ = repo.DefaultDB.ChannelConfig(g.ChanID, "scoring", DefaultScoreStrategy)
+	g.scoring = scoreStrategy(g.scoringName)
+	g.hints = g.configBool("hints", HintEnabled)
+	g.skipPercent = g.configInt("skipPercent", SkipPercent)
+	g.answerBurst = g.configInt("answerBurst", AnswerBurst)
+	g.answerRefill = time.Duration(g.configInt("answerRefillMs", int(AnswerRefill/time.Millisecond))) * time.Millisecond
+	g.limiter = newAnswerLimiter(g.answerBurst, g.answerRefill)
+}
+
+// play the game starting from round `from` until it is finished
+func (g *Game) play(from int) {
+	for i := from; i <= RoundPerGame; i++ {
+		if g.lostLease() {
+			return
+		}
+		skipped, err := g.startRound(i)
+		if err != nil {
+			log.Error("starting round failed", zap.String("chanID", g.ChanID), zap.Error(err))
+		}
+		if skipped {
+			// replacement question doesn't count as a round
+			i--
+			g.saveCheckpoint(i+1, nil)
+			time.Sleep(DelayBetweenRound)
+			continue
+		}
+		if i == RoundPerGame {
+			break
+		}
+		g.saveCheckpoint(i+1, nil)
+		g.publish(RankMessage{GameID: g.id, ChanID: g.ChanID, Round: i, Rank: g.rank, TeamRank: g.teamRank, Breakdown: g.roundDetails, Recap: g.roundRecap})
+		time.Sleep(DelayBetweenRound)
+	}
+	if g.lostLease() {
+		return
+	}
+
+	// the final rank is still pending until it is published, a resumed game continues from here
+	g.finalizing = true
+	g.saveCheckpoint(RoundPerGame, nil)
+	if tied := g.tiedLeaders(); len(tied) > 1 && g.configBool("suddenDeath", SuddenDeathEnabled) {
+		g.publish(RankMessage{GameID: g.id, ChanID: g.ChanID, Round: RoundPerGame, Rank: g.rank, TeamRank: g.teamRank, Breakdown: g.roundDetails, Recap: g.roundRecap})
+		time.Sleep(DelayBetweenRound)
+		g.playSuddenDeath(RoundPerGame+1, tied)
+	}
+	g.publish(RankMessage{GameID: g.id, ChanID: g.ChanID, Round: RoundPerGame, Rank: g.finalRank(), TeamRank: g.teamRank, Final: true, Breakdown: g.roundDetails, Recap: g.roundRecap})
+	g.State = Finished
+	g.deleteCheckpoint()
+	switch {
+	case g.Daily != "":
+		g.saveDailyScore()
+	case g.Practice:
+		g.savePracticeScore()
+	}
+	g.publish(StateMessage{GameID: g.id, ChanID: g.ChanID, State: Finished})
+	log.Info("Game finished", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id))
+}
+
+// tiedLeaders returns players that share the first place
+func (g *Game) tiedLeaders() model.Rank {
+	var tied model.Rank
+	for _, ps := range g.rank {
+		if ps.Score > 0 && ps.Score == g.rank[0].Score {
+			tied = append(tied, ps)
+		}
+	}
+
+	return tied
+}
+
+// playSuddenDeath plays one more round where only tied players can answer.
+// The first player to answer correctly wins the game
+func (g *Game) playSuddenDeath(currentRound int, tied model.Rank) {
+	g.suddenDeath = make(map[model.PlayerID]bool)
+	for _, ps := range tied {
+		g.suddenDeath[ps.PlayerID] = true
+	}
+	g.publish(SuddenDeathMessage{GameID: g.id, ChanID: g.ChanID, Players: tied})
+	log.Info("Sudden death", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int("players", len(tied)))
+
+	if _, err := g.startRound(currentRound); err != nil {
+		log.Error("starting sudden death round failed", zap.String("chanID", g.ChanID), zap.Error(err))
+	}
+
+	msg := SuddenDeathMessage{GameID: g.id, ChanID: g.ChanID, Players: tied, Finished: true}
+	for _, ps := range tied {
+		if ps.PlayerID == g.suddenDeathWinner {
+			msg.Winner = ps
+		}
+	}
+	g.suddenDeath = nil
+	g.publish(msg)
+}
+
+// finalRank orders players with the same score by the sudden death winner,
+// then the earliest correct answer and then the most correct answers
+func (g *Game) finalRank() model.Rank {
+	rank := make(model.Rank, len(g.rank))
+	copy(rank, g.rank)
+	sort.SliceStable(rank, func(i, j int) bool {
+		a, b := rank[i], rank[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if aWon, bWon := a.PlayerID == g.suddenDeathWinner, b.PlayerID == g.suddenDeathWinner; aWon != bWon {
+			return aWon
+		}
+		if aAt, bAt := g.firstAnswerAt[a.PlayerID], g.firstAnswerAt[b.PlayerID]; !aAt.Equal(bAt) {
+			if aAt.IsZero() || bAt.IsZero() {
+				return bAt.IsZero()
 			}
+			return aAt.Before(bAt)
 		}
-		g.State = Finished
-		g.Out <- StateMessage{ChanID: g.ChanID, State: Finished, GameID: g.id}
-		log.Info("Game finished", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id))
-	}()
+		if aCount, bCount := g.answerCount[a.PlayerID], g.answerCount[b.PlayerID]; aCount != bCount {
+			return aCount > bCount
+		}
+		return a.PlayerID < b.PlayerID
+	})
+	for i := range rank {
+		rank[i].Position = i + 1
+	}
+
+	return rank
+}
+
+// setupTeams enables team mode when there are at least 2 teams
+func (g *Game) setupTeams() {
+	names := make(map[string]bool)
+	for _, team := range g.teams {
+		names[team] = true
+	}
+	if len(names) < 2 {
+		g.teams = make(map[model.PlayerID]string)
+		return
+	}
+
+	for team := range names {
+		g.teamNames = append(g.teamNames, team)
+		g.teamRank = append(g.teamRank, model.TeamScore{Team: team})
+	}
+	sort.Strings(g.teamNames)
+	g.teamRank = g.teamRank.Add(nil)
+	g.steal = g.configBool("steal", StealEnabled)
+	log.Info("Team mode", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int("teams", len(g.teamNames)), zap.Bool("steal", g.steal))
 }
 
-func (g *Game) startRound(currentRound int) error {
-	g.totalRoundPlayed++
-	if err := repo.DefaultDB.IncRoundPlayed(g.ChanID); err != nil {
-		log.Error("failed to increase totalRoundPlayed", zap.Int("totalRoundPlayed", g.totalRoundPlayed), zap.Error(err))
+// playerTeam returns the team of a player. Player that didn't join a team
+// is assigned to the team with the least members
+func (g *Game) playerTeam(p model.Player) string {
+	if team, ok := g.teams[p.ID]; ok {
+		return team
 	}
 
-	questionLimit := DefaultQuestionLimit
-	if limitConf, err := repo.DefaultDB.ChannelConfig(g.ChanID, "questionLimit", ""); err == nil && limitConf != "" {
-		if limit, err := strconv.ParseInt(limitConf, 10, 64); err == nil {
-			questionLimit = int(limit)
+	members := make(map[string]int)
+	for _, team := range g.teams {
+		members[team]++
+	}
+	team := g.teamNames[0]
+	for _, name := range g.teamNames {
+		if members[name] < members[team] {
+			team = name
 		}
 	}
+	g.teams[p.ID] = team
+	log.Debug("player assigned to team", zap.String("chanID", g.ChanID), zap.String("playerID", string(p.ID)), zap.String("team", team))
+
+	return team
+}
 
-	question, err := g.questionDB.NextQuestion(g.seed, g.totalRoundPlayed, questionLimit)
+// canAnswer returns true if player is allowed to answer in the current state of the round
+func (g *Game) canAnswer(p model.Player, r *round) bool {
+	if g.Daily != "" && g.dailyCompleted(p.ID) {
+		return false
+	}
+	if r.suddenDeath {
+		return g.suddenDeath[p.ID]
+	}
+	if len(g.teamNames) == 0 {
+		return true
+	}
+
+	team := g.playerTeam(p)
+	switch {
+	case r.state == RoundSteal:
+		return team == r.stealing
+	case r.playing != "":
+		return team == r.playing
+	}
+
+	return true
+}
+
+// startSteal gives the next team a chance to steal the round points.
+// returns false if steal is not possible
+func (g *Game) startSteal(r *round, currentRound int) bool {
+	if !g.steal || r.playing == "" || r.finished() {
+		return false
+	}
+
+	for i, team := range g.teamNames {
+		if team == r.playing {
+			r.stealing = g.teamNames[(i+1)%len(g.teamNames)]
+			break
+		}
+	}
+	r.state = RoundSteal
+	r.endAt = time.Now().Add(StealDuration).Round(time.Second)
+	g.publish(StateMessage{GameID: g.id, ChanID: g.ChanID, State: RoundSteal, Round: currentRound, Team: r.stealing})
+	log.Info("Round steal", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int64("roundID", r.id), zap.String("team", r.stealing))
+
+	return true
+}
+
+func (g *Game) configBool(key string, defaultValue bool) bool {
+	conf, err := repo.DefaultDB.ChannelConfig(g.ChanID, key, "")
+	if err != nil || conf == "" {
+		return defaultValue
+	}
+	v, err := strconv.ParseBool(conf)
 	if err != nil {
-		return errors.Wrap(err, "failed to get the next question")
+		return defaultValue
+	}
+
+	return v
+}
+
+func (g *Game) configInt(key string, defaultValue int) int {
+	conf, err := repo.DefaultDB.ChannelConfig(g.ChanID, key, "")
+	if err != nil || conf == "" {
+		return defaultValue
+	}
+	v, err := strconv.ParseInt(conf, 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return int(v)
+}
+
+// startRound plays one round. skipped is true if players voted to skip the question
+func (g *Game) startRound(currentRound int) (skipped bool, err error) {
+	questionLimit := g.configInt("questionLimit", DefaultQuestionLimit)
+	if g.fixedSequence {
+		questionLimit = 0
+	}
+
+	var question qna.Question
+	cp := g.resume
+	g.resume = nil
+	if cp != nil && cp.QuestionID != 0 {
+		// continue the round that was interrupted
+		question, err = g.questionDB.GetQuestion(strconv.Itoa(cp.QuestionID))
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get the resumed question")
+		}
+	} else {
+		g.totalRoundPlayed++
+		// a fixed sequence does not move the sequence of the channel
+		if !g.fixedSequence {
+			if err := repo.DefaultDB.IncRoundPlayed(g.ChanID); err != nil {
+				log.Error("failed to increase totalRoundPlayed", zap.Int("totalRoundPlayed", g.totalRoundPlayed), zap.Error(err))
+			}
+		}
+
+		question, err = g.questionDB.NextQuestion(g.seed, g.totalRoundPlayed, questionLimit)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get the next question")
+		}
+		g.incQuestionStats(question.ID, "played")
 	}
 
 	r, err := newRound(question, g.players)
 	if err != nil {
-		return err
+		return false, err
 	}
+	roundDuration := RoundDuration
+	if cp != nil && cp.QuestionID == question.ID {
+		copy(r.correct, cp.Correct)
+		copy(r.answeredAt, cp.AnsweredAt)
+		copy(r.hints, cp.Hints)
+		// the answer times are measured from the original start, the round continues with the time left
+		if !cp.StartedAt.IsZero() {
+			r.startedAt, r.endAt = cp.StartedAt, cp.EndAt
+			roundDuration = time.Until(cp.EndAt)
+		}
+	}
+	r.number = currentRound
+	r.suddenDeath = len(g.suddenDeath) > 0
+	r.scoring = g.scoring
+	r.strikeLimit = g.boardStrikeLimit
 
 	g.currentRound = r
 	r.state = RoundStarted
-	timeUp := time.After(RoundDuration)
+	if g.steal && !r.suddenDeath {
+		r.playing = g.teamNames[(currentRound-1)%len(g.teamNames)]
+	}
+	timeUp := time.After(roundDuration)
 	timeLeftTick := time.NewTicker(tickDuration)
 	displayAnswerTick := time.NewTicker(tickDuration)
 
+	roundData := JournalRoundData{Round: currentRound, Question: question, Playing: r.playing, StartedAt: r.startedAt}
+	for pID := range g.suddenDeath {
+		roundData.SuddenDeath = append(roundData.SuddenDeath, pID)
+	}
+	g.record(JournalRound, roundData)
+	g.saveCheckpoint(currentRound, r)
+
 	// print question
-	g.Out <- StateMessage{ChanID: g.ChanID, State: RoundStarted, Round: currentRound, RoundText: r.questionText(g.ChanID, false), GameID: g.id}
+	g.publish(StateMessage{GameID: g.id, ChanID: g.ChanID, State: RoundStarted, Round: currentRound, RoundText: r.questionText(g.ChanID, g.id, false), Team: r.playing})
 	log.Info("Round Started", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int64("roundID", r.id), zap.Int("questionID", r.q.ID), zap.Int("questionLimit", questionLimit))
 
 	for {
 		select {
 		case rawMsg := <-g.In: // new answer coming from player
 			started := time.Now()
-			msg, ok := rawMsg.(TextMessage)
-			if !ok {
+			var msg TextMessage
+			switch m := rawMsg.(type) {
+			case TextMessage:
+				if m.ReceivedAt.IsZero() {
+					m.ReceivedAt = started
+				}
+				g.record(JournalAnswer, m)
+				msg = m
+			case SkipVoteMessage:
+				g.record(JournalSkipVote, m)
+				if !g.voteSkip(m, r) {
+					continue
+				}
+				timeLeftTick.Stop()
+				displayAnswerTick.Stop()
+				g.skipRound(r, currentRound)
+
+				return true, nil
+			default:
 				log.Error("Unexpected message type input from client")
 				continue
 			}
 			gameLatencyTimer.UpdateSince(msg.ReceivedAt)
 
 			handled := g.handleMessage(msg, r)
+			if r.state == RoundSteal && r.stealGuessed {
+				timeLeftTick.Stop()
+				displayAnswerTick.Stop()
+				g.publish(StealMessage{GameID: g.id, ChanID: g.ChanID, Round: currentRound, Team: r.stealing, Player: msg.Player, Success: r.stolenBy != ""})
+				g.revealRound(r, currentRound)
+				gameFinishedTimer.UpdateSince(started)
+
+				return false, nil
+			}
+			if r.state == RoundStarted && g.boardStrikeLimit > 0 && r.strikes >= g.boardStrikeLimit {
+				log.Info("Board strike limit reached", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int64("roundID", r.id), zap.Int("strikes", r.strikes))
+				if g.startSteal(r, currentRound) {
+					timeUp = time.After(StealDuration)
+					continue
+				}
+				timeLeftTick.Stop()
+				displayAnswerTick.Stop()
+				g.revealRound(r, currentRound)
+				gameFinishedTimer.UpdateSince(started)
+
+				return false, nil
+			}
 			if handled {
 				gameMsgProcessTimer.UpdateSince(started)
 				gameServiceTimer.UpdateSince(msg.ReceivedAt)
 				continue
 			}
 
+			g.saveCheckpoint(currentRound, r)
+
+			if r.suddenDeath {
+				// first correct answer wins
+				timeLeftTick.Stop()
+				displayAnswerTick.Stop()
+				g.suddenDeathWinner = msg.Player.ID
+				r.state = RoundFinished
+				showUnAnswered := true
+				g.publish(r.questionText(g.ChanID, g.id, showUnAnswered))
+				log.Info("Sudden death finished", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.String("winner", string(msg.Player.ID)))
+
+				return false, nil
+			}
+
 			if r.finished() {
 				timeLeftTick.Stop()
 				displayAnswerTick.Stop()
 				g.showAnswer(r)
 				r.state = RoundFinished
-				err := g.updateRanking(r.ranking())
+				err := g.updateRanking(r)
 				if err != nil {
 					log.Error("failed to update ranking", zap.Error(err))
 				}
 
-				g.Out <- StateMessage{ChanID: g.ChanID, State: RoundFinished, Round: currentRound, GameID: g.id}
+				g.publish(StateMessage{GameID: g.id, ChanID: g.ChanID, State: RoundFinished, Round: currentRound})
 				log.Info("Round finished", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int64("roundID", r.id), zap.Bool("timeout", false))
 				gameFinishedTimer.UpdateSince(started)
 
-				return nil
+				return false, nil
 			}
 			gameMsgProcessTimer.UpdateSince(started)
 			gameServiceTimer.UpdateSince(msg.ReceivedAt)
 
 		case <-timeLeftTick.C: // inform time left
-			select {
-			case g.Out <- TickMessage{ChanID: g.ChanID, TimeLeft: r.timeLeft()}:
-			default:
+			if g.hints && r.state == RoundStarted && r.updateHint(RoundDuration) {
+				g.showHint(r)
 			}
+			g.publish(TickMessage{GameID: g.id, ChanID: g.ChanID, TimeLeft: r.timeLeft()})
 
 		case <-displayAnswerTick.C: // show correct answer (at most once every 10s)
 			g.showAnswer(r)
 
 		case <-timeUp: // time is up
+			if r.state == RoundStarted && g.startSteal(r, currentRound) {
+				timeUp = time.After(StealDuration)
+				continue
+			}
 			timeLeftTick.Stop()
 			displayAnswerTick.Stop()
-			g.State = RoundFinished
-			err := g.updateRanking(r.ranking())
-			if err != nil {
-				log.Error("failed to update ranking", zap.Error(err))
-			}
+			g.revealRound(r, currentRound)
 
-			g.Out <- StateMessage{ChanID: g.ChanID, State: RoundTimeout, Round: currentRound, GameID: g.id}
-			log.Info("Round finished", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int64("roundID", r.id), zap.Bool("timeout", true))
-			showUnAnswered := true
-			g.Out <- r.questionText(g.ChanID, showUnAnswered)
-
-			return nil
+			return false, nil
 		}
 	}
 }
 
+// voteSkip registers the player vote to skip the round. returns true if there are enough votes
+func (g *Game) voteSkip(msg SkipVoteMessage, r *round) bool {
+	if r.state != RoundStarted || r.suddenDeath || g.skipped >= MaxSkipPerGame || r.skipVotes[msg.Player.ID] {
+		return false
+	}
+	if !msg.ReceivedAt.IsZero() && msg.ReceivedAt.Before(r.startedAt) {
+		// vote for the previous round
+		return false
+	}
+	playerActiveMap.Set(string(msg.Player.ID), struct{}{}, cache.DefaultExpiration)
+	r.active[msg.Player.ID] = true
+	r.skipVotes[msg.Player.ID] = true
+
+	needed := (len(r.active)*g.skipPercent + 99) / 100
+	if needed < 1 {
+		needed = 1
+	}
+	votes := len(r.skipVotes)
+	g.publish(SkipVoteMessage{GameID: g.id, ChanID: g.ChanID, Round: r.number, Player: msg.Player, Votes: votes, Needed: needed})
+	log.Info("Skip vote", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int64("roundID", r.id), zap.Int("votes", votes), zap.Int("needed", needed))
+
+	return votes >= needed
+}
+
+// skipRound ends the round early and reveals the answers. Points gained in the round are kept
+func (g *Game) skipRound(r *round, currentRound int) {
+	g.skipped++
+	r.state = RoundSkipped
+	if err := g.updateRanking(r); err != nil {
+		log.Error("failed to update ranking", zap.Error(err))
+	}
+	g.incQuestionStats(r.q.ID, "skipped")
+
+	g.publish(StateMessage{GameID: g.id, ChanID: g.ChanID, State: RoundSkipped, Round: currentRound})
+	log.Info("Round skipped", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int64("roundID", r.id), zap.Int("questionID", r.q.ID))
+	showUnAnswered := true
+	g.publish(r.questionText(g.ChanID, g.id, showUnAnswered))
+}
+
+func (g *Game) incQuestionStats(questionID int, key string) {
+	if err := repo.DefaultDB.IncQuestionStats(questionID, key); err != nil {
+		log.Error("failed to increase question stats", zap.Int("questionID", questionID), zap.String("key", key), zap.Error(err))
+	}
+}
+
+// revealRound finishes a round that was not fully answered and shows the unanswered answers
+func (g *Game) revealRound(r *round, currentRound int) {
+	g.State = RoundFinished
+	err := g.updateRanking(r)
+	if err != nil {
+		log.Error("failed to update ranking", zap.Error(err))
+	}
+
+	g.publish(StateMessage{GameID: g.id, ChanID: g.ChanID, State: RoundTimeout, Round: currentRound})
+	log.Info("Round finished", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int64("roundID", r.id), zap.Bool("timeout", true))
+	showUnAnswered := true
+	g.publish(r.questionText(g.ChanID, g.id, showUnAnswered))
+}
+
 func (g *Game) handleMessage(msg TextMessage, r *round) (handled bool) {
 	playerActiveMap.Set(string(msg.Player.ID), struct{}{}, cache.DefaultExpiration)
+	r.active[msg.Player.ID] = true
 	log.Debug("startRound got message", zap.String("chanID", g.ChanID), zap.Object("msg", msg))
+	if !g.canAnswer(msg.Player, r) {
+		return true
+	}
+	g.participate(msg.Player)
+	if g.strikeLimit > 0 && r.playerStrikes[msg.Player.ID] >= g.strikeLimit {
+		// locked out until the next round
+		return true
+	}
 	answer := msg.Text
-	correct, alreadyAnswered, idx := r.answer(msg.Player, answer)
+	receivedAt := msg.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+	if !g.limiter.allow(msg.Player.ID, receivedAt) {
+		answerThrottledCount.Inc(1)
+		g.flag(r, msg.Player, FlagFlood)
+		return true
+	}
+	correct, alreadyAnswered, idx := r.answer(msg.Player, answer, receivedAt)
+	if correct && !alreadyAnswered && MinAnswerTime > 0 && receivedAt.Sub(r.startedAt) < MinAnswerTime {
+		g.flag(r, msg.Player, FlagFast)
+	}
+	if correct && !alreadyAnswered {
+		if _, ok := g.firstAnswerAt[msg.Player.ID]; !ok {
+			g.firstAnswerAt[msg.Player.ID] = receivedAt
+		}
+		g.answerCount[msg.Player.ID]++
+		g.lastAnswerAt[msg.Player.ID] = receivedAt
+	}
+	if r.state == RoundSteal {
+		// stealing team only gets one guess
+		r.stealGuessed = true
+		if correct && !alreadyAnswered {
+			r.stolenBy = r.stealing
+		}
+		return true
+	}
 	if !correct {
+		r.strike(msg.Player)
+		if g.strikeLimit > 0 || g.boardStrikeLimit > 0 {
+			strikes := r.playerStrikes[msg.Player.ID]
+			g.publish(StrikeMessage{
+				GameID:           g.id,
+				ChanID:           g.ChanID,
+				Round:            r.number,
+				Player:           msg.Player,
+				Strikes:          strikes,
+				Locked:           g.strikeLimit > 0 && strikes >= g.strikeLimit,
+				BoardStrikes:     r.strikes,
+				BoardStrikeLimit: g.boardStrikeLimit,
+			})
+		}
 		if TickAfterWrongAnswer {
-			g.Out <- WrongAnswerMessage{ChanID: g.ChanID, TimeLeft: r.timeLeft()}
+			g.publish(WrongAnswerMessage{GameID: g.id, ChanID: g.ChanID, TimeLeft: r.timeLeft()})
 		}
 		return true
 	}
@@ -295,21 +993,98 @@ func (g *Game) handleMessage(msg TextMessage, r *round) (handled bool) {
 		zap.String("chanID", g.ChanID),
 		zap.Int64("gameID", g.id),
 		zap.Int64("roundID", r.id))
+	g.publish(AnswerMessage{
+		GameID:     g.id,
+		ChanID:     g.ChanID,
+		Round:      r.number,
+		Player:     msg.Player,
+		Answer:     r.q.Answers[idx].String(),
+		Score:      r.q.Answers[idx].Score,
+		Top:        r.topAnswer(idx),
+		Board:      len(r.q.Answers),
+		Answered:   r.answeredBy(msg.Player.ID),
+		Elapsed:    receivedAt.Sub(r.startedAt),
+		ReceivedAt: receivedAt,
+	})
 
 	return false
 }
 
-func (g *Game) updateRanking(r model.Rank) error {
-	g.rank = g.rank.Add(r)
-	err := repo.DefaultDB.SaveScore(g.ChanID, g.chanName, r)
+func (g *Game) updateRanking(r *round) error {
+	if r.suddenDeath {
+		// sudden death only decides the winner, the final rank has no round to recap
+		g.roundDetails = nil
+		g.roundRecap = RoundRecap{}
+		return nil
+	}
+	if len(g.teamNames) > 0 {
+		g.teamRank = g.teamRank.Add(r.teamRanking(g.teams))
+	}
+	g.roundDetails = r.scoreDetails()
+	g.roundRecap = r.recap()
+	rank := r.ranking()
+	g.rank = g.rank.Add(rank)
+	if g.Practice || g.Daily != "" {
+		// saved once the game is finished
+		return nil
+	}
+	if g.lostLease() {
+		return errors.New("channel lease lost, score is not saved")
+	}
+	err := repo.DefaultDB.SaveScore(g.ChanID, g.chanName, rank)
 
 	return errors.Wrap(err, "failed to save score")
 }
 
+// savePracticeScore saves the game score of each player to the practice ranking
+func (g *Game) savePracticeScore() {
+	if g.lostLease() {
+		return
+	}
+	for _, ps := range g.rank {
+		best, err := repo.DefaultDB.SavePracticeScore(ps)
+		if err != nil {
+			log.Error("failed to save practice score", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Error(err))
+			continue
+		}
+		if best {
+			log.Info("Practice best score", zap.String("chanID", g.ChanID), zap.String("playerID", string(ps.PlayerID)), zap.Int("score", ps.Score))
+		}
+	}
+}
+
+// lostLease returns true if another instance has taken over the channel
+func (g *Game) lostLease() bool {
+	if g.Fence == 0 || g.stale {
+		return g.stale
+	}
+	ok, err := repo.DefaultDB.ValidFence(g.ChanID, g.Fence)
+	if err != nil {
+		log.Error("failed to validate lease", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Error(err))
+		return false
+	}
+	if !ok {
+		g.stale = true
+		log.Warn("channel lease lost, stopping game", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Int64("fence", g.Fence))
+	}
+
+	return g.stale
+}
+
 func (g *Game) CurrentQuestion() qna.Question {
 	return g.currentRound.q
 }
 
+// showHint shows the board with hints of unanswered answers
+func (g *Game) showHint(r *round) {
+	log.Debug("show hint", zap.String("chanID", g.ChanID), zap.Int64("roundID", r.id), zap.Int("level", r.hintLevel))
+	g.record(JournalHint, JournalHintData{Level: r.hintLevel})
+	g.publish(r.questionText(g.ChanID, g.id, false))
+	for i := range r.highlight {
+		r.highlight[i] = false
+	}
+}
+
 func (g *Game) showAnswer(r *round) {
 	var show bool
 	// if there is no highlighted answer don't display
@@ -323,11 +1098,8 @@ func (g *Game) showAnswer(r *round) {
 		return
 	}
 
-	qnaText := r.questionText(g.ChanID, false)
-	select {
-	case g.Out <- qnaText:
-	default:
-	}
+	qnaText := r.questionText(g.ChanID, g.id, false)
+	g.publish(qnaText)
 
 	for i := range r.highlight {
 		r.highlight[i] = false
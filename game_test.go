@@ -2,7 +2,10 @@ package fam100
 
 import (
 	"math/rand"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/yulrizka/fam100/model"
 	"github.com/yulrizka/fam100/qna"
@@ -53,7 +56,7 @@ func Test_Game(t *testing.T) {
 				player = value
 				break
 			}
-			r.answer(player, answerText)
+			r.answer(player, answerText, time.Now())
 		}
 		// no checking, just to debug output
 		/*
@@ -67,3 +70,39 @@ func Test_Game(t *testing.T) {
 		*/
 	})
 }
+
+func TestNormalizeTeam(t *testing.T) {
+	long := strings.Repeat("é", MaxTeamName+5)
+	team := NormalizeTeam(long)
+	if !utf8.ValidString(team) {
+		t.Errorf("team %q is not valid utf-8", team)
+	}
+	if want, got := MaxTeamName, utf8.RuneCountInString(team); want != got {
+		t.Errorf("team length want %d got %d", want, got)
+	}
+	if want, got := "merah", NormalizeTeam("  Merah "); want != got {
+		t.Errorf("team want %s got %s", want, got)
+	}
+}
+
+func TestAnswerers(t *testing.T) {
+	now := time.Now()
+	g := &Game{
+		rank: model.Rank{
+			{PlayerID: "1", Name: "foo", Score: 30},
+			{PlayerID: "2", Name: "bar", Score: 20},
+		},
+		lastAnswerAt: map[model.PlayerID]time.Time{"1": now, "2": now.Add(-time.Hour)},
+	}
+
+	players := g.Answerers(now.Add(-time.Minute))
+	if want, got := 1, len(players); want != got {
+		t.Fatalf("answerers want %d got %d", want, got)
+	}
+	if want, got := "foo", players[0].Name; want != got {
+		t.Errorf("name want %s got %s", want, got)
+	}
+	if want, got := 2, len(g.Answerers(now.Add(-2*time.Hour))); want != got {
+		t.Errorf("answerers want %d got %d", want, got)
+	}
+}
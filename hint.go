@@ -0,0 +1,74 @@
+package fam100
+
+import (
+	"time"
+	"unicode"
+)
+
+// Hint configuration
+var (
+	HintEnabled = false
+	// HintPenalty is the percentage of points deducted for each hint level shown before the answer
+	HintPenalty = 20
+	// HintAt is the fraction of round duration left for each hint level
+	HintAt = []float64{0.6, 0.4, 0.2}
+)
+
+// hintLevel returns the hint level that should be shown for the time left in the round
+func hintLevel(timeLeft, duration time.Duration) int {
+	level := 0
+	for i, at := range HintAt {
+		if float64(timeLeft) <= at*float64(duration) {
+			level = i + 1
+		}
+	}
+
+	return level
+}
+
+// hintText masks the answer based on hint level.
+// 1 shows letter count, 2 shows the first letter of each word and 3 shows more letters
+func hintText(s string, level int) string {
+	if level <= 0 {
+		return ""
+	}
+
+	runes := []rune(s)
+	hint := make([]rune, len(runes))
+	for i, c := range runes {
+		switch {
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			hint[i] = c
+		case level >= 2 && (i == 0 || runes[i-1] == ' '):
+			hint[i] = c
+		case level >= 3 && i%3 == 2:
+			hint[i] = c
+		default:
+			hint[i] = '_'
+		}
+	}
+
+	return string(hint)
+}
+
+// hintPoints deducts the points of an answer that was answered after hint was shown
+func hintPoints(points, level int) int {
+	points = points * (100 - level*HintPenalty) / 100
+	if points < 0 {
+		return 0
+	}
+
+	return points
+}
+
+// updateHint raise hint level of the round based on the time left.
+// returns true if the level has changed
+func (r *round) updateHint(duration time.Duration) bool {
+	level := hintLevel(r.timeLeft(), duration)
+	if level <= r.hintLevel {
+		return false
+	}
+	r.hintLevel = level
+
+	return true
+}
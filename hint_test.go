@@ -0,0 +1,48 @@
+package fam100
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHintText(t *testing.T) {
+	tests := []struct {
+		text  string
+		level int
+		want  string
+	}{
+		{"burung hantu", 0, ""},
+		{"burung hantu", 1, "______ _____"},
+		{"burung hantu", 2, "b_____ h____"},
+		{"burung hantu", 3, "b_r__g ha__u"},
+	}
+
+	for _, tt := range tests {
+		if got := hintText(tt.text, tt.level); got != tt.want {
+			t.Errorf("hintText(%q, %d) want %q got %q", tt.text, tt.level, tt.want, got)
+		}
+	}
+}
+
+func TestHintLevel(t *testing.T) {
+	duration := 100 * time.Second
+	tests := []struct {
+		timeLeft time.Duration
+		want     int
+	}{
+		{90 * time.Second, 0},
+		{60 * time.Second, 1},
+		{30 * time.Second, 2},
+		{10 * time.Second, 3},
+	}
+
+	for _, tt := range tests {
+		if got := hintLevel(tt.timeLeft, duration); got != tt.want {
+			t.Errorf("hintLevel(%s) want %d got %d", tt.timeLeft, tt.want, got)
+		}
+	}
+
+	if want, got := 16, hintPoints(20, 1); want != got {
+		t.Errorf("hintPoints want %d got %d", want, got)
+	}
+}
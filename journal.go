@@ -0,0 +1,208 @@
+package fam100
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/zap"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/qna"
+)
+
+// JournalDir is the directory for game journals, empty disables the journal
+var JournalDir = ""
+
+// JournalKind is the type of journal entry
+type JournalKind string
+
+// Available JournalKind
+const (
+	// input and engine events
+	JournalGame     JournalKind = "game"
+	JournalRound    JournalKind = "round"
+	JournalAnswer   JournalKind = "answer"
+	JournalSkipVote JournalKind = "skipVote"
+	JournalHint     JournalKind = "hint"
+
+	// game output
+	JournalState       JournalKind = "state"
+	JournalBoard       JournalKind = "board"
+	JournalRank        JournalKind = "rank"
+	JournalTick        JournalKind = "tick"
+	JournalStrike      JournalKind = "strike"
+	JournalSteal       JournalKind = "steal"
+	JournalSuddenDeath JournalKind = "suddenDeath"
+	JournalSkipResult  JournalKind = "skipResult"
+	JournalCorrect     JournalKind = "correct"
+	JournalOther       JournalKind = "other"
+)
+
+// JournalEntry is a single recorded event of a game
+type JournalEntry struct {
+	GameID int64           `json:"gameID"`
+	At     time.Time       `json:"at"`
+	Kind   JournalKind     `json:"kind"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// JournalGameData is the configuration of the game when it started
+type JournalGameData struct {
+	ChanID           string                    `json:"chanID"`
+	ChanName         string                    `json:"chanName"`
+	Seed             int64                     `json:"seed"`
+	Teams            map[model.PlayerID]string `json:"teams"`
+	Steal            bool                      `json:"steal"`
+	StrikeLimit      int                       `json:"strikeLimit"`
+	BoardStrikeLimit int                       `json:"boardStrikeLimit"`
+	Scoring          string                    `json:"scoring"`
+	Hints            bool                      `json:"hints"`
+	SkipPercent      int                       `json:"skipPercent"`
+	AnswerBurst      int                       `json:"answerBurst"`
+	AnswerRefill     time.Duration             `json:"answerRefill"`
+}
+
+// JournalRoundData is the question and state when a round started
+type JournalRoundData struct {
+	Round       int              `json:"round"`
+	Question    qna.Question     `json:"question"`
+	Playing     string           `json:"playing"`
+	SuddenDeath []model.PlayerID `json:"suddenDeath"`
+	StartedAt   time.Time        `json:"startedAt"`
+}
+
+// JournalHintData is the hint level shown to the players
+type JournalHintData struct {
+	Level int `json:"level"`
+}
+
+// Journal records game events in append-only fashion
+type Journal interface {
+	Record(e JournalEntry) error
+	Close() error
+}
+
+// FileJournal writes one JSON entry per line to a file per game
+type FileJournal struct {
+	f   *os.File
+	enc *json.Encoder
+}
+
+// JournalPath returns file path of a game journal
+func JournalPath(dir string, gameID int64) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.jsonl", gameID))
+}
+
+// OpenFileJournal opens journal of the game for appending
+func OpenFileJournal(dir string, gameID int64) (*FileJournal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create journal directory")
+	}
+	f, err := os.OpenFile(JournalPath(dir, gameID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open journal")
+	}
+
+	return &FileJournal{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (j *FileJournal) Record(e JournalEntry) error {
+	return j.enc.Encode(e)
+}
+
+func (j *FileJournal) Close() error {
+	return j.f.Close()
+}
+
+// ReadJournal reads all entries of a journal file
+func ReadJournal(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %q", path)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for i := 1; s.Scan(); i++ {
+		var e JournalEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, errors.Wrapf(err, "invalid entry at line %d", i)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, s.Err()
+}
+
+// openJournal opens the journal of the game if it is enabled
+func (g *Game) openJournal() {
+	if JournalDir == "" {
+		return
+	}
+	j, err := OpenFileJournal(JournalDir, g.id)
+	if err != nil {
+		log.Error("failed to open journal", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Error(err))
+		return
+	}
+	g.journal = j
+}
+
+// closeJournal closes the journal of the game when it stops playing
+func (g *Game) closeJournal() {
+	if g.journal == nil {
+		return
+	}
+	if err := g.journal.Close(); err != nil {
+		log.Error("failed to close journal", zap.String("chanID", g.ChanID), zap.Int64("gameID", g.id), zap.Error(err))
+	}
+	g.journal = nil
+}
+
+// record writes an entry to the game journal if it is enabled
+func (g *Game) record(kind JournalKind, data interface{}) {
+	if g.journal == nil {
+		return
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Error("failed to encode journal entry", zap.String("chanID", g.ChanID), zap.String("kind", string(kind)), zap.Error(err))
+		return
+	}
+	e := JournalEntry{GameID: g.id, At: time.Now(), Kind: kind, Data: raw}
+	if err := g.journal.Record(e); err != nil {
+		log.Error("failed to write journal entry", zap.String("chanID", g.ChanID), zap.String("kind", string(kind)), zap.Error(err))
+	}
+}
+
+// journalKind returns the journal kind of a game event
+func journalKind(e Event) JournalKind {
+	switch e.(type) {
+	case StateMessage:
+		return JournalState
+	case QNAMessage:
+		return JournalBoard
+	case RankMessage:
+		return JournalRank
+	case TickMessage, WrongAnswerMessage:
+		return JournalTick
+	case StrikeMessage:
+		return JournalStrike
+	case StealMessage:
+		return JournalSteal
+	case SuddenDeathMessage:
+		return JournalSuddenDeath
+	case SkipVoteMessage:
+		return JournalSkipResult
+	case AnswerMessage:
+		return JournalCorrect
+	}
+
+	return JournalOther
+}
@@ -0,0 +1,86 @@
+package fam100
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/qna"
+	"github.com/yulrizka/fam100/repo"
+)
+
+type memoryJournal struct {
+	entries []JournalEntry
+}
+
+func (m *memoryJournal) Record(e JournalEntry) error {
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+func (m *memoryJournal) Close() error { return nil }
+
+func TestReplay(t *testing.T) {
+	oDB := repo.DefaultDB
+	defer func() { repo.DefaultDB = oDB }()
+	repo.DefaultDB = new(repo.MemoryDB)
+
+	// apa yang orang katakan sambil memegang pipi orang lain*33:gemes*20:lucu*12:sayang*...
+	q := testQuestion(t, "1")
+	startedAt := time.Now()
+	j := new(memoryJournal)
+	g := &Game{id: 1, ChanID: "1", journal: j}
+	g.record(JournalGame, JournalGameData{ChanID: "1", Scoring: "first"})
+	g.record(JournalRound, JournalRoundData{Round: 1, Question: q, StartedAt: startedAt})
+	g.record(JournalAnswer, TextMessage{Player: model.Player{ID: "1", Name: "foo"}, Text: "lucu", ReceivedAt: startedAt.Add(time.Second)})
+	g.record(JournalAnswer, TextMessage{Player: model.Player{ID: "2", Name: "bar"}, Text: "gemes", ReceivedAt: startedAt.Add(2 * time.Second)})
+	g.record(JournalAnswer, TextMessage{Player: model.Player{ID: "2", Name: "bar"}, Text: "lucu", ReceivedAt: startedAt.Add(3 * time.Second)})
+	g.record(JournalState, StateMessage{State: RoundTimeout, Round: 1})
+
+	var steps []ReplayStep
+	if err := Replay(j.entries, func(s ReplayStep) { steps = append(steps, s) }); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := len(j.entries), len(steps); want != got {
+		t.Fatalf("steps want %d got %d", want, got)
+	}
+
+	last := steps[len(steps)-1]
+	if want, got := 2, len(last.Rank); want != got {
+		t.Fatalf("len(rank) want %d got %d", want, got)
+	}
+	if want, got := model.PlayerID("2"), last.Rank[0].PlayerID; want != got {
+		t.Errorf("rank[0] want %s got %s", want, got)
+	}
+	// first answer bonus
+	if want, got := 30, last.Rank[1].Score; want != got {
+		t.Errorf("rank[1].Score want %d got %d", want, got)
+	}
+	if !last.Board.ShowUnanswered {
+		t.Errorf("board should be revealed at the end of the round")
+	}
+}
+
+func TestJournalNotOpenedBeforeStart(t *testing.T) {
+	oDB, oDir := repo.DefaultDB, JournalDir
+	defer func() { repo.DefaultDB, JournalDir = oDB, oDir }()
+	repo.DefaultDB = new(repo.MemoryDB)
+	JournalDir = t.TempDir()
+
+	questionDB, err := qna.NewText("qna/questions.txt")
+	if err != nil {
+		t.Fatalf("failed to load questions: %v", err)
+	}
+	g, err := NewGame("1", "journal", make(chan Message), questionDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a lobby that never starts does not hold a journal file
+	if g.journal != nil {
+		t.Errorf("journal should be opened when the game starts")
+	}
+	if _, err := os.Stat(JournalPath(JournalDir, g.id)); !os.IsNotExist(err) {
+		t.Errorf("journal file should not exist before start, got %v", err)
+	}
+}
@@ -9,4 +9,8 @@ var (
 	gameLatencyTimer    = metrics.NewRegisteredTimer("game.latency.ns", metrics.DefaultRegistry)
 	gameFinishedTimer   = metrics.NewRegisteredTimer("game.finished.ns", metrics.DefaultRegistry)
 	playerActive        = metrics.NewRegisteredGauge("player.active", metrics.DefaultRegistry)
+	eventDroppedCount   = metrics.NewRegisteredCounter("game.event.dropped", metrics.DefaultRegistry)
+
+	answerThrottledCount = metrics.NewRegisteredCounter("answer.throttled.count", metrics.DefaultRegistry)
+	playerFlaggedCount   = metrics.NewRegisteredCounter("player.flagged.count", metrics.DefaultRegistry)
 )
@@ -0,0 +1,7 @@
+package model
+
+// Rating is the skill rating of a player
+type Rating struct {
+	Value float64 `json:"value"`
+	Games int     `json:"games"` // rated games played
+}
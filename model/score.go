@@ -9,11 +9,64 @@ type PlayerScore struct {
 	Position int      `json:"position"`
 }
 
+// RankMode is how positions are given to players with the same score
+type RankMode int
+
+// Available RankMode
+const (
+	// StandardRank skips positions after a tie (1, 2, 2, 4)
+	StandardRank RankMode = iota
+	// DenseRank does not skip positions after a tie (1, 2, 2, 3)
+	DenseRank
+)
+
+// ParseRankMode returns the RankMode of a config value, "dense" or "standard"
+func ParseRankMode(s string) RankMode {
+	if s == "dense" {
+		return DenseRank
+	}
+	return StandardRank
+}
+
 type Rank []PlayerScore
 
-func (r Rank) Len() int           { return len(r) }
-func (r Rank) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
-func (r Rank) Less(i, j int) bool { return r[i].Score > r[j].Score }
+func (r Rank) Len() int      { return len(r) }
+func (r Rank) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+
+// Less orders by the highest score. Players with the same score are ordered by
+// descending player ID, the same order as a redis sorted set in reverse, so a
+// ranking cut at any length is the same in memory and in redis
+func (r Rank) Less(i, j int) bool {
+	if r[i].Score == r[j].Score {
+		return r[i].PlayerID > r[j].PlayerID
+	}
+	return r[i].Score > r[j].Score
+}
+
+// Sort sorts the ranking and sets the position of each player
+func (r Rank) Sort(mode RankMode) {
+	sort.Sort(r)
+	r.SetPosition(mode)
+}
+
+// SetPosition sets the position of each player of a sorted ranking, players with the
+// same score share a position
+func (r Rank) SetPosition(mode RankMode) {
+	pos := 0
+	for i := range r {
+		if i > 0 && r[i].Score == r[i-1].Score {
+			r[i].Position = r[i-1].Position
+			continue
+		}
+		switch mode {
+		case DenseRank:
+			pos++
+		default:
+			pos = i + 1
+		}
+		r[i].Position = pos
+	}
+}
 
 func (r Rank) Add(source Rank) Rank {
 	lookup := make(map[PlayerID]PlayerScore)
@@ -35,10 +88,7 @@ func (r Rank) Add(source Rank) Rank {
 	for _, v := range lookup {
 		result = append(result, v)
 	}
-	sort.Sort(result)
-	for i := 0; i < len(result); i++ {
-		result[i].Position = i + 1
-	}
+	result.Sort(StandardRank)
 	return result
 }
 
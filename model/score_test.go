@@ -61,3 +61,39 @@ func TestSubtract(t *testing.T) {
 		t.Errorf("r3[2] want %d got %d", want, got)
 	}
 }
+
+func TestRankSort(t *testing.T) {
+	rank := Rank{
+		{PlayerID: "a", Score: 5},
+		{PlayerID: "b", Score: 9},
+		{PlayerID: "c", Score: 5},
+		{PlayerID: "d", Score: 1},
+	}
+
+	tests := []struct {
+		mode     RankMode
+		position []int
+	}{
+		{StandardRank, []int{1, 2, 2, 4}},
+		{DenseRank, []int{1, 2, 2, 3}},
+	}
+	for _, tt := range tests {
+		rank.Sort(tt.mode)
+		for i, want := range []PlayerID{"b", "c", "a", "d"} {
+			if got := rank[i].PlayerID; want != got {
+				t.Errorf("mode %d rank[%d] want %s got %s", tt.mode, i, want, got)
+			}
+			if want, got := tt.position[i], rank[i].Position; want != got {
+				t.Errorf("mode %d rank[%d].Position want %d got %d", tt.mode, i, want, got)
+			}
+		}
+	}
+
+	// positions of a cut ranking are the same as the full ranking
+	cut := make(Rank, 2)
+	copy(cut, rank[:2])
+	cut.Sort(StandardRank)
+	if want, got := 2, cut[1].Position; want != got {
+		t.Errorf("cut rank[1].Position want %d got %d", want, got)
+	}
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// PlayerStats is the record of a player in a channel or in all channels
+type PlayerStats struct {
+	Games      int64         `json:"games"`
+	Rounds     int64         `json:"rounds"` // rounds with a correct answer of the player
+	Correct    int64         `json:"correct"`
+	TopAnswers int64         `json:"topAnswers"` // correct answers with the highest score of the board
+	AnswerTime time.Duration `json:"answerTime"` // total time of correct answers since the question was shown
+	BestGame   int           `json:"bestGame"`   // highest score in a game
+}
+
+// Add returns the sum of both stats, best game is the highest of both
+func (s PlayerStats) Add(o PlayerStats) PlayerStats {
+	s.Games += o.Games
+	s.Rounds += o.Rounds
+	s.Correct += o.Correct
+	s.TopAnswers += o.TopAnswers
+	s.AnswerTime += o.AnswerTime
+	if o.BestGame > s.BestGame {
+		s.BestGame = o.BestGame
+	}
+
+	return s
+}
+
+// AverageAnswerTime returns the average time of a correct answer
+func (s PlayerStats) AverageAnswerTime() time.Duration {
+	if s.Correct == 0 {
+		return 0
+	}
+
+	return s.AnswerTime / time.Duration(s.Correct)
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlayerStatsAdd(t *testing.T) {
+	s := PlayerStats{Games: 1, Rounds: 3, Correct: 4, TopAnswers: 1, AnswerTime: 20 * time.Second, BestGame: 50}
+	s = s.Add(PlayerStats{Games: 1, Rounds: 2, Correct: 1, AnswerTime: 5 * time.Second, BestGame: 30})
+
+	want := PlayerStats{Games: 2, Rounds: 5, Correct: 5, TopAnswers: 1, AnswerTime: 25 * time.Second, BestGame: 50}
+	if s != want {
+		t.Errorf("want %+v got %+v", want, s)
+	}
+	if want, got := 5*time.Second, s.AverageAnswerTime(); want != got {
+		t.Errorf("average answer time, want %s got %s", want, got)
+	}
+	if want, got := time.Duration(0), (PlayerStats{}).AverageAnswerTime(); want != got {
+		t.Errorf("average answer time without answer, want %s got %s", want, got)
+	}
+}
@@ -0,0 +1,47 @@
+package model
+
+import "sort"
+
+// TeamScore is the accumulated score of a team
+type TeamScore struct {
+	Team     string `json:"team"`
+	Score    int    `json:"score"`
+	Position int    `json:"position"`
+}
+
+type TeamRank []TeamScore
+
+func (r TeamRank) Len() int      { return len(r) }
+func (r TeamRank) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r TeamRank) Less(i, j int) bool {
+	if r[i].Score == r[j].Score {
+		return r[i].Team < r[j].Team
+	}
+	return r[i].Score > r[j].Score
+}
+
+func (r TeamRank) Add(source TeamRank) TeamRank {
+	lookup := make(map[string]TeamScore)
+	for _, v := range r {
+		lookup[v.Team] = v
+	}
+
+	for _, s := range source {
+		if ts, ok := lookup[s.Team]; !ok {
+			lookup[s.Team] = s
+		} else {
+			ts.Score += s.Score
+			lookup[s.Team] = ts
+		}
+	}
+
+	result := make(TeamRank, 0, len(lookup))
+	for _, v := range lookup {
+		result = append(result, v)
+	}
+	sort.Sort(result)
+	for i := 0; i < len(result); i++ {
+		result[i].Position = i + 1
+	}
+	return result
+}
@@ -0,0 +1,25 @@
+package model
+
+import "testing"
+
+func TestTeamRankAdd(t *testing.T) {
+	r1 := TeamRank{{Team: "merah", Score: 3}, {Team: "biru"}}
+	r2 := TeamRank{{Team: "biru", Score: 5}}
+
+	r3 := r1.Add(r2)
+	if want, got := 2, len(r3); want != got {
+		t.Fatalf("len(r3) want %d got %d", want, got)
+	}
+	if want, got := "biru", r3[0].Team; want != got {
+		t.Errorf("r3[0].Team want %s got %s", want, got)
+	}
+	if want, got := 5, r3[0].Score; want != got {
+		t.Errorf("r3[0].Score want %d got %d", want, got)
+	}
+	if want, got := 2, r3[1].Position; want != got {
+		t.Errorf("r3[1].Position want %d got %d", want, got)
+	}
+	if want, got := 0, r1[1].Score; want != got {
+		t.Errorf("r1[1].Score want %d got %d", want, got)
+	}
+}
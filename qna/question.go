@@ -26,12 +26,34 @@ type Provider interface {
 	Count() (int, error)
 }
 
+// Categorizer is implemented by providers that have category metadata
+type Categorizer interface {
+	// Categories returns sorted names of the categories
+	Categories() []string
+
+	// InCategory returns a provider with questions of the categories only
+	InCategory(categories ...string) (Provider, error)
+}
+
 // Question for a round
 type Question struct {
-	ID      int
-	Text    string
-	Answers []Answer
-	lookup  map[string]int
+	ID       int
+	Text     string
+	Category string
+	Answers  []Answer
+	lookup   map[string]int
+}
+
+// NewQuestion creates a question with lookup for its answers
+func NewQuestion(id int, text string, answers []Answer) Question {
+	q := Question{ID: id, Text: text, Answers: answers, lookup: make(map[string]int)}
+	for i, a := range answers {
+		for _, text := range a.Text {
+			q.lookup[strings.TrimSpace(strings.ToLower(text))] = i
+		}
+	}
+
+	return q
 }
 
 // Check answers gives the score for particular answer to a question
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
 )
 
+const categoryPrefix = "category:"
+
 type Text struct {
 	questions    []Question
 	questionsMap map[string]Question
@@ -29,13 +32,27 @@ func NewText(path string) (*Text, error) {
 	}
 	s := bufio.NewScanner(f)
 	i := 0
+	category := ""
 	for s.Scan() {
 		i++
+		// "# category: name" applies to the following questions
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+			if strings.HasPrefix(strings.ToLower(line), categoryPrefix) {
+				category = NormalizeCategory(line[len(categoryPrefix):])
+			}
+			continue
+		}
 		q, ok := scanQuestionRaw(s.Text())
 		if !ok {
 			continue
 		}
 		q.ID = i
+		q.Category = category
 		if err := t.AddQuestion(q); err != nil {
 			return nil, errors.Wrapf(err, "failed adding question at line %d", i)
 		}
@@ -70,16 +87,61 @@ func (t *Text) NextQuestion(seed int64, played int, questionLimit int) (q Questi
 
 	r := rand.New(rand.NewSource(seed + ExtraQuestionSeed))
 	order := r.Perm(questionSize)
-	id := order[played%questionLimit] + 1 // order is 0 based
-	idStr := strconv.FormatInt(int64(id), 10)
 
-	return t.GetQuestion(idStr)
+	// id is the line number, which differs from the order when the file has comments
+	return t.questions[order[played%questionLimit]], nil
 }
 
 func (t *Text) Count() (int, error) {
 	return len(t.questions), nil
 }
 
+// NormalizeCategory returns the category name as it is used for lookup
+func NormalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// Categories returns sorted names of the categories, empty if questions have no category
+func (t *Text) Categories() []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, q := range t.questions {
+		if q.Category == "" || seen[q.Category] {
+			continue
+		}
+		seen[q.Category] = true
+		categories = append(categories, q.Category)
+	}
+	sort.Strings(categories)
+
+	return categories
+}
+
+// InCategory returns questions of the categories. The questions keep their id
+func (t *Text) InCategory(categories ...string) (Provider, error) {
+	wanted := make(map[string]bool)
+	for _, c := range categories {
+		wanted[NormalizeCategory(c)] = true
+	}
+
+	sub := &Text{
+		questions:    make([]Question, 0),
+		questionsMap: make(map[string]Question),
+	}
+	for _, q := range t.questions {
+		if wanted[q.Category] {
+			if err := sub.AddQuestion(q); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(sub.questions) == 0 {
+		return nil, fmt.Errorf("no question in categories %s", strings.Join(categories, ", "))
+	}
+
+	return sub, nil
+}
+
 func scanQuestionRaw(s string) (Question, bool) {
 	var q Question
 
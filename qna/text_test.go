@@ -1,6 +1,9 @@
 package qna
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -24,3 +27,60 @@ func TestNewText(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestCategories(t *testing.T) {
+	f, err := ioutil.TempFile("", "fam100_qna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	fmt.Fprint(f, `sebutkan sesuatu yang bisa meletus*39:balon*25:gunung*
+# Category: Hewan
+hewan apa yang sering dikaitkan dengan hal mistik*28:burung hantu*21:burung gagak*
+hewan yang bisa terbang*30:burung*20:kelelawar*
+
+# category: makanan
+makanan khas padang*40:rendang*30:sate*
+`)
+	f.Close()
+
+	text, err := NewText(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "[hewan makanan]", fmt.Sprint(text.Categories()); want != got {
+		t.Errorf("categories want %s got %s", want, got)
+	}
+
+	p, err := text.InCategory("Hewan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 2, mustCount(t, p); want != got {
+		t.Errorf("count want %d got %d", want, got)
+	}
+	for i := 0; i < 4; i++ {
+		q, err := p.NextQuestion(1, i, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Category != "hewan" {
+			t.Errorf("question %d %q is not in category hewan", q.ID, q.Text)
+		}
+		if got, err := text.GetQuestion(fmt.Sprint(q.ID)); err != nil || got.Text != q.Text {
+			t.Errorf("question %d should keep its id, got %q %v", q.ID, got.Text, err)
+		}
+	}
+
+	if _, err := text.InCategory("olahraga"); err == nil {
+		t.Errorf("unknown category should fail")
+	}
+}
+
+func mustCount(t *testing.T, p Provider) int {
+	n, err := p.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
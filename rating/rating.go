@@ -0,0 +1,83 @@
+// Package rating computes the skill rating of players from their placement in games.
+//
+// It is a multiplayer Elo: a game of n players is scored as if every player had
+// played a match against each other player, winning against the players placed
+// below, losing against the players placed above and drawing with players of the
+// same position. The change of the rating is scaled by n-1 so a game is worth the
+// same regardless of the number of players.
+package rating
+
+import (
+	"math"
+
+	"github.com/yulrizka/fam100/model"
+)
+
+// Rating parameters
+var (
+	Initial          = 1500.0 // rating of a new player
+	K                = 32.0   // maximum change of the rating in a game
+	ProvisionalGames = 10     // games before the rating is reliable
+)
+
+// New returns the rating of a new player
+func New() model.Rating {
+	return model.Rating{Value: Initial}
+}
+
+// Provisional returns whether the player has not played enough games for a reliable rating
+func Provisional(r model.Rating) bool {
+	return r.Games < ProvisionalGames
+}
+
+// Expected returns the expected score of a player with rating a against rating b,
+// 1 is a certain win and 0 is a certain loss
+func Expected(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// Update returns the new ratings of the players of a game ranked by their final
+// position, so the tie-breakers and sudden death of the final ranking decide tied
+// scores. Players without a rating start with the initial rating. A provisional
+// rating changes twice as fast so new players quickly reach their level. Games
+// with less than two players are not rated
+func Update(rank model.Rank, ratings map[model.PlayerID]model.Rating) map[model.PlayerID]model.Rating {
+	updated := make(map[model.PlayerID]model.Rating, len(rank))
+	if len(rank) < 2 {
+		return updated
+	}
+
+	current := func(id model.PlayerID) model.Rating {
+		if r, ok := ratings[id]; ok {
+			return r
+		}
+		return New()
+	}
+	for _, a := range rank {
+		ra := current(a.PlayerID)
+		var delta float64
+		for _, b := range rank {
+			if a.PlayerID == b.PlayerID {
+				continue
+			}
+			actual := 0.5
+			switch {
+			case a.Position < b.Position:
+				actual = 1
+			case a.Position > b.Position:
+				actual = 0
+			}
+			delta += actual - Expected(ra.Value, current(b.PlayerID).Value)
+		}
+		k := K
+		if Provisional(ra) {
+			k *= 2
+		}
+		updated[a.PlayerID] = model.Rating{
+			Value: ra.Value + k*delta/float64(len(rank)-1),
+			Games: ra.Games + 1,
+		}
+	}
+
+	return updated
+}
@@ -0,0 +1,101 @@
+package rating
+
+import (
+	"math"
+	"testing"
+
+	"github.com/yulrizka/fam100/model"
+)
+
+func established(value float64) model.Rating {
+	return model.Rating{Value: value, Games: ProvisionalGames}
+}
+
+func TestExpected(t *testing.T) {
+	if want, got := 0.5, Expected(1500, 1500); want != got {
+		t.Errorf("equal rating, want %f got %f", want, got)
+	}
+	if got := Expected(1900, 1500); math.Abs(got-0.909) > 0.001 {
+		t.Errorf("400 points higher, want 0.909 got %f", got)
+	}
+	if got := Expected(1500, 1600) + Expected(1600, 1500); math.Abs(got-1) > 1e-9 {
+		t.Errorf("expected scores should add up to 1, got %f", got)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	rank := model.Rank{
+		{PlayerID: "1", Score: 100, Position: 1},
+		{PlayerID: "2", Score: 50, Position: 2},
+		{PlayerID: "3", Score: 50, Position: 2},
+	}
+	ratings := map[model.PlayerID]model.Rating{
+		"1": established(1500),
+		"2": established(1500),
+		"3": established(1500),
+	}
+
+	updated := Update(rank, ratings)
+	// winner beats both: (1-0.5)*2 * 32 / 2
+	if want, got := 1516.0, updated["1"].Value; want != got {
+		t.Errorf("winner, want %f got %f", want, got)
+	}
+	// lost to the winner, draw with the other
+	if want, got := 1492.0, updated["2"].Value; want != got {
+		t.Errorf("second, want %f got %f", want, got)
+	}
+	if updated["2"].Value != updated["3"].Value {
+		t.Errorf("tied players should get the same rating, got %f and %f", updated["2"].Value, updated["3"].Value)
+	}
+	if want, got := ProvisionalGames+1, updated["1"].Games; want != got {
+		t.Errorf("games, want %d got %d", want, got)
+	}
+
+	var sum float64
+	for _, r := range updated {
+		sum += r.Value
+	}
+	if want := 4500.0; math.Abs(sum-want) > 1e-9 {
+		t.Errorf("established ratings should be zero sum, want %f got %f", want, sum)
+	}
+}
+
+func TestUpdateProvisional(t *testing.T) {
+	rank := model.Rank{
+		{PlayerID: "new", Score: 100, Position: 1},
+		{PlayerID: "old", Score: 50, Position: 2},
+	}
+	updated := Update(rank, map[model.PlayerID]model.Rating{"old": established(1500)})
+
+	if want, got := 1532.0, updated["new"].Value; want != got {
+		t.Errorf("new player changes twice as fast, want %f got %f", want, got)
+	}
+	if want, got := 1484.0, updated["old"].Value; want != got {
+		t.Errorf("established player, want %f got %f", want, got)
+	}
+	if !Provisional(updated["new"]) {
+		t.Errorf("rating after one game should be provisional")
+	}
+}
+
+func TestUpdateTieBreak(t *testing.T) {
+	// same score, the position decided by the tie-breaker wins
+	rank := model.Rank{
+		{PlayerID: "1", Score: 50, Position: 1},
+		{PlayerID: "2", Score: 50, Position: 2},
+	}
+	updated := Update(rank, map[model.PlayerID]model.Rating{"1": established(1500), "2": established(1500)})
+	if want, got := 1516.0, updated["1"].Value; want != got {
+		t.Errorf("winner of the tie-breaker, want %f got %f", want, got)
+	}
+	if want, got := 1484.0, updated["2"].Value; want != got {
+		t.Errorf("loser of the tie-breaker, want %f got %f", want, got)
+	}
+}
+
+func TestUpdateSinglePlayer(t *testing.T) {
+	updated := Update(model.Rank{{PlayerID: "1", Score: 100}}, nil)
+	if want, got := 0, len(updated); want != got {
+		t.Errorf("single player game should not be rated, got %v", updated)
+	}
+}
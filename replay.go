@@ -0,0 +1,136 @@
+package fam100
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/qna"
+)
+
+// ReplayStep is the state of the game after a journal entry is replayed
+type ReplayStep struct {
+	Entry    JournalEntry
+	Board    *QNAMessage // nil before the first round
+	Rank     model.Rank
+	TeamRank model.TeamRank
+}
+
+// Replay re-runs journal entries through the game engine and calls step after every entry.
+// Scores are saved through repo.DefaultDB, it should be set to a non persistent database such as repo.MemoryDB
+func Replay(entries []JournalEntry, step func(ReplayStep)) error {
+	g := &Game{
+		State:         Started,
+		players:       make(map[model.PlayerID]model.Player),
+		teams:         make(map[model.PlayerID]string),
+		firstAnswerAt: make(map[model.PlayerID]time.Time),
+		lastAnswerAt:  make(map[model.PlayerID]time.Time),
+		answerCount:   make(map[model.PlayerID]int),
+		scoring:       RawScore{},
+	}
+
+	var r *round
+	for i, e := range entries {
+		var err error
+		switch e.Kind {
+		case JournalGame:
+			var d JournalGameData
+			if err = json.Unmarshal(e.Data, &d); err != nil {
+				break
+			}
+			g.id, g.ChanID, g.chanName, g.seed = e.GameID, d.ChanID, d.ChanName, d.Seed
+			for pID, team := range d.Teams {
+				g.teams[pID] = team
+			}
+			g.setupTeams()
+			g.steal = d.Steal
+			g.strikeLimit, g.boardStrikeLimit = d.StrikeLimit, d.BoardStrikeLimit
+			g.scoringName, g.scoring = d.Scoring, scoreStrategy(d.Scoring)
+			g.hints, g.skipPercent = d.Hints, d.SkipPercent
+			g.limiter = newAnswerLimiter(d.AnswerBurst, d.AnswerRefill)
+
+		case JournalRound:
+			var d JournalRoundData
+			if err = json.Unmarshal(e.Data, &d); err != nil {
+				break
+			}
+			q := qna.NewQuestion(d.Question.ID, d.Question.Text, d.Question.Answers)
+			if r, err = newRound(q, g.players); err != nil {
+				break
+			}
+			g.suddenDeath = nil
+			if len(d.SuddenDeath) > 0 {
+				g.suddenDeath = make(map[model.PlayerID]bool)
+				for _, pID := range d.SuddenDeath {
+					g.suddenDeath[pID] = true
+				}
+			}
+			r.state = RoundStarted
+			r.number = d.Round
+			r.playing = d.Playing
+			r.startedAt = d.StartedAt
+			r.scoring = g.scoring
+			r.strikeLimit = g.boardStrikeLimit
+			r.suddenDeath = len(g.suddenDeath) > 0
+			g.currentRound = r
+
+		case JournalAnswer:
+			var msg TextMessage
+			if err = json.Unmarshal(e.Data, &msg); err != nil || r == nil {
+				break
+			}
+			if handled := g.handleMessage(msg, r); !handled && r.suddenDeath {
+				g.suddenDeathWinner = msg.Player.ID
+				r.state = RoundFinished
+			}
+
+		case JournalSkipVote:
+			var msg SkipVoteMessage
+			if err = json.Unmarshal(e.Data, &msg); err != nil || r == nil {
+				break
+			}
+			g.voteSkip(msg, r)
+
+		case JournalHint:
+			var d JournalHintData
+			if err = json.Unmarshal(e.Data, &d); err != nil || r == nil {
+				break
+			}
+			r.hintLevel = d.Level
+
+		case JournalState:
+			var msg StateMessage
+			if err = json.Unmarshal(e.Data, &msg); err != nil || r == nil {
+				break
+			}
+			switch msg.State {
+			case RoundSteal:
+				r.state = RoundSteal
+				r.stealing = msg.Team
+			case RoundSkipped:
+				g.skipped++
+				fallthrough
+			case RoundFinished, RoundTimeout:
+				if r.state != RoundFinished {
+					err = g.updateRanking(r)
+				}
+				r.state = RoundFinished
+			case Finished:
+				g.State = Finished
+			}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to replay entry %d (%s)", i+1, e.Kind)
+		}
+
+		s := ReplayStep{Entry: e, Rank: g.finalRank(), TeamRank: g.teamRank}
+		if r != nil {
+			board := r.questionText(g.ChanID, g.id, r.state == RoundFinished)
+			s.Board = &board
+		}
+		step(s)
+	}
+
+	return nil
+}
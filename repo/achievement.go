@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/yulrizka/fam100/model"
+)
+
+// The streak of a player is the number of consecutive days ending at the last
+// day the player played. Playing twice on the same day keeps the streak.
+
+var playStreakScript = redis.NewScript(1, `
+local last = redis.call('HGET', KEYS[1], 'day')
+if last == ARGV[1] then
+	return tonumber(redis.call('HGET', KEYS[1], 'streak'))
+end
+local streak = 1
+if last == ARGV[2] then
+	streak = tonumber(redis.call('HGET', KEYS[1], 'streak')) + 1
+end
+redis.call('HMSET', KEYS[1], 'day', ARGV[1], 'streak', streak)
+return streak
+`)
+
+// PlayStreak records that the player played on day and returns the streak of consecutive days
+func (r RedisDB) PlayStreak(playerID model.PlayerID, day, yesterday string) (int64, error) {
+	defer dbPlayStreakTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(playStreakScript.Do(conn, streakKey+string(playerID), day, yesterday))
+}
+
+// AddBadge awards the badge to the player, it returns false if the player already has the badge
+func (r RedisDB) AddBadge(playerID model.PlayerID, badge string, at time.Time) (bool, error) {
+	defer dbAddBadgeTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("HSETNX", badgeKey+string(playerID), badge, at.Unix()))
+}
+
+// Badges returns the badges of the player and the time they were awarded
+func (r RedisDB) Badges(playerID model.PlayerID) (map[string]time.Time, error) {
+	defer dbBadgesTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Int64Map(conn.Do("HGETALL", badgeKey+string(playerID)))
+	if err != nil {
+		return nil, err
+	}
+	badges := make(map[string]time.Time, len(values))
+	for badge, at := range values {
+		badges[badge] = time.Unix(at, 0)
+	}
+
+	return badges, nil
+}
@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/yulrizka/fam100/model"
+)
+
+// DailyRetention is how long the ranking of a day is kept
+var DailyRetention = 90 * 24 * time.Hour
+
+// The daily challenge is completed once per player per day. The first score of the
+// day goes to the ranking of the day and is added to the all-time daily ranking.
+
+var saveDailyScript = redis.NewScript(4, `
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[4], ARGV[1], ARGV[3])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+redis.call('ZINCRBY', KEYS[3], ARGV[2], ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+redis.call('PEXPIRE', KEYS[2], ARGV[4])
+return 1
+`)
+
+// SaveDailyScore completes the daily challenge of the player, it returns false if the
+// player already completed the challenge of the day
+func (r RedisDB) SaveDailyScore(day string, ps model.PlayerScore) (first bool, err error) {
+	defer dbSaveDailyScoreTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(saveDailyScript.Do(conn,
+		dailyDoneKey+day, dailyRankKey+day, dailyTotalKey, pNameKey,
+		ps.PlayerID, ps.Score, ps.Name, int64(DailyRetention/time.Millisecond),
+	))
+}
+
+// DailyCompleted returns whether the player completed the daily challenge of the day
+func (r RedisDB) DailyCompleted(day string, playerID model.PlayerID) (bool, error) {
+	defer dbDailyCompletedTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("HEXISTS", dailyDoneKey+day, playerID))
+}
+
+// DailyRanking returns the ranking of the daily challenge of the day
+func (r RedisDB) DailyRanking(day string, limit int) (model.Rank, error) {
+	return r.getRanking(dailyRankKey+day, limit-1, model.StandardRank)
+}
+
+// DailyTotalRanking returns the sum of daily challenge scores of all days
+func (r RedisDB) DailyTotalRanking(limit int) (model.Rank, error) {
+	return r.getRanking(dailyTotalKey, limit-1, model.StandardRank)
+}
@@ -1,17 +1,25 @@
 package repo
 
 import (
+	"context"
+	"time"
+
 	"github.com/yulrizka/fam100/model"
 )
 
 type db interface {
 	Reset() error
 	Init() (err error)
-	ChannelRanking(chanID string, limit int) (ranking model.Rank, err error)
+	ChannelRanking(chanID string, period Period, limit int) (ranking model.Rank, err error)
+	ChannelRankingAt(chanID string, period Period, t time.Time, limit int) (ranking model.Rank, err error)
+	ChannelRankingPage(chanID string, period Period, offset, limit int) (ranking model.Rank, err error)
+	ChannelRankingAround(chanID string, period Period, playerID model.PlayerID, n int) (ranking model.Rank, err error)
 	ChannelCount() (total int, err error)
 	Channels() (channels map[string]string, err error)
 	ChannelConfig(chanID, key, defaultValue string) (config string, err error)
 	GlobalConfig(key, defaultValue string) (config string, err error)
+	SetChannelConfig(chanID, key, value string) error
+	ChannelRankMode(chanID string) model.RankMode
 
 	PlayerCount() (total int, err error)
 	PlayerChannelScore(chanID string, playerID model.PlayerID) (model.PlayerScore, error)
@@ -19,25 +27,100 @@ type db interface {
 	// Stats command
 	IncStats(key string) error
 	IncChannelStats(chanID, key string) error
-	IncPlayerStats(playerID model.PlayerID, key string) error
+	IncPlayerStats(playerID model.PlayerID, key string) (int64, error)
 	Stats(key string) (interface{}, error)
 	ChannelStats(chanID, key string) (interface{}, error)
-	PlayerStats(playerID, key string) (interface{}, error)
+	IncQuestionStats(questionID int, key string) error
+	QuestionStats(questionID int) (map[string]int, error)
+
+	// player record of the games, chanID is empty for all channels
+	SavePlayerStats(chanID string, playerID model.PlayerID, game model.PlayerStats) error
+	PlayerStats(chanID string, playerID model.PlayerID) (model.PlayerStats, error)
+
+	// skill rating, players with less than minGames are not in the ranking
+	Ratings(playerIDs []model.PlayerID) (map[model.PlayerID]model.Rating, error)
+	UpdateRatings(playerIDs []model.PlayerID, minGames int, update func(map[model.PlayerID]model.Rating) map[model.PlayerID]model.Rating) error
+	RatingRanking(limit int) (model.Rank, error)
+
+	// checkpoint of running games and lobbies, kind is either "game" or "lobby"
+	SaveCheckpoint(kind, chanID string, data []byte) error
+	DeleteCheckpoint(kind, chanID string) error
+	Checkpoints(kind string) (map[string][]byte, error)
+	CheckpointChannels(kind string) ([]string, error)
+	Checkpoint(kind, chanID string) ([]byte, error)
+
+	// channel ownership between bot instances
+	AcquireLease(chanID, instanceID string, ttl time.Duration) (token int64, err error)
+	RenewLease(chanID, instanceID string, token int64, ttl time.Duration) (bool, error)
+	ReleaseLease(chanID, instanceID string, token int64) error
+	LeaseOwner(chanID string) (instanceID string, err error)
+	ValidFence(chanID string, token int64) (bool, error)
+	Publish(instanceID string, data []byte) error
+	Subscribe(ctx context.Context, instanceID string) (<-chan []byte, error)
+
+	// suspicious players
+	FlagPlayer(chanID string, playerID model.PlayerID, reason string) error
+	FlaggedPlayers(chanID string) (map[model.PlayerID]map[string]int, error)
+
+	// webhook delivery log
+	AddWebhookDelivery(data []byte, keep int) error
+	WebhookDeliveries(limit int) ([][]byte, error)
+
+	// scheduled games, spec is parsed by the schedule package
+	AddSchedule(chanID, chanName, spec string) error
+	RemoveSchedule(chanID, spec string) error
+	Schedules() (map[string]map[string]string, error)
+
+	// tournaments, results are kept apart so that instances can report at the same time
+	SaveTournament(id string, data []byte) error
+	DeleteTournament(id string) error
+	Tournaments() (map[string][]byte, error)
+	Tournament(id string) ([]byte, error)
+	SetTournamentResult(id, key string, value int64) error
+	TournamentResults(id string) (map[string]int64, error)
 
 	NextGame(chanID string) (seed int64, nextRound int, err error)
 	IncRoundPlayed(chanID string) error
 
 	// scores
 	SaveScore(chanID, chanName string, scores model.Rank) error
-	PlayerRanking(limit int) (model.Rank, error)
+	PlayerRanking(period Period, limit int) (model.Rank, error)
 	PlayerScore(playerID model.PlayerID) (ps model.PlayerScore, err error)
+
+	// practice games in private chat
+	SavePracticeScore(ps model.PlayerScore) (best bool, err error)
+	PracticeRanking(limit int) (model.Rank, error)
+	PracticeScore(playerID model.PlayerID) (model.PlayerScore, error)
+
+	// daily challenge, day is formatted as 2006-01-02
+	SaveDailyScore(day string, ps model.PlayerScore) (first bool, err error)
+	DailyCompleted(day string, playerID model.PlayerID) (bool, error)
+	DailyRanking(day string, limit int) (model.Rank, error)
+	DailyTotalRanking(limit int) (model.Rank, error)
+
+	// achievements, badge is the id of the badge
+	PlayStreak(playerID model.PlayerID, day, yesterday string) (streak int64, err error)
+	AddBadge(playerID model.PlayerID, badge string, at time.Time) (added bool, err error)
+	Badges(playerID model.PlayerID) (map[string]time.Time, error)
+
+	// seasons of the all-time rankings, chanID is empty for all channels
+	Season() (int, error)
+	StartSeason() (archived int, err error)
+	SeasonRanking(chanID string, season, limit int) (model.Rank, error)
 }
 
 var (
 	RedisPrefix = "fam100"
 
 	gStatsKey, cStatsKey, pStatsKey, cRankKey, pNameKey, pRankKey string
-	cNameKey, cConfigKey, gConfigKey                              string
+	cNameKey, cConfigKey, gConfigKey, qStatsKey, checkpointKey    string
+	leaseKey, leaseTokenKey, fenceKey, inboxKey, webhookLogKey    string
+	cFlagKey, scheduleKey, scheduleChanKey, practiceRankKey       string
+	tournamentKey, tournamentResultKey                            string
+	dailyDoneKey, dailyRankKey, dailyTotalKey                     string
+	streakKey, badgeKey, pRecordKey, ratingKey, ratingRankKey     string
+	cPeriodRankKey, pPeriodRankKey                                string
+	seasonKey, cSeasonRankKey, pSeasonRankKey                     string
 )
 
 // DefaultDB default question database
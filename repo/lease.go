@@ -0,0 +1,175 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/pkg/errors"
+)
+
+// A lease gives one bot instance the ownership of a channel. Each lease is
+// granted with a fencing token that only increases, writes that carry a token
+// older than the latest one granted for the channel are stale.
+
+var (
+	acquireLeaseScript = redis.NewScript(3, `
+local v = redis.call('GET', KEYS[1])
+if v then
+	local owner, token = string.match(v, '^(.*):(%d+)$')
+	if owner == ARGV[1] then
+		redis.call('PEXPIRE', KEYS[1], ARGV[2])
+		return tonumber(token)
+	end
+	return 0
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], ARGV[1] .. ':' .. token, 'PX', ARGV[2])
+redis.call('SET', KEYS[3], token)
+return token
+`)
+
+	renewLeaseScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+	releaseLeaseScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+)
+
+func leaseValue(instanceID string, token int64) string {
+	return fmt.Sprintf("%s:%d", instanceID, token)
+}
+
+// AcquireLease acquires the ownership of a channel for ttl. It returns the fencing token
+// or 0 if the channel is owned by another instance. Acquiring an owned lease extends it.
+func (r RedisDB) AcquireLease(chanID, instanceID string, ttl time.Duration) (token int64, err error) {
+	defer dbAcquireLeaseTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(acquireLeaseScript.Do(conn, leaseKey+chanID, leaseTokenKey, fenceKey+chanID, instanceID, int64(ttl/time.Millisecond)))
+}
+
+// RenewLease extends the lease of the channel, it returns false if the lease was lost
+func (r RedisDB) RenewLease(chanID, instanceID string, token int64, ttl time.Duration) (bool, error) {
+	defer dbRenewLeaseTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(renewLeaseScript.Do(conn, leaseKey+chanID, leaseValue(instanceID, token), int64(ttl/time.Millisecond)))
+}
+
+// ReleaseLease gives up the ownership of the channel
+func (r RedisDB) ReleaseLease(chanID, instanceID string, token int64) error {
+	defer dbReleaseLeaseTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := releaseLeaseScript.Do(conn, leaseKey+chanID, leaseValue(instanceID, token))
+
+	return err
+}
+
+// LeaseOwner returns the instance that owns the channel, empty if nobody owns it
+func (r RedisDB) LeaseOwner(chanID string) (instanceID string, err error) {
+	defer dbLeaseOwnerTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	v, err := redis.String(conn.Do("GET", leaseKey+chanID))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	i := strings.LastIndex(v, ":")
+	if i < 0 {
+		return "", errors.Errorf("invalid lease %q", v)
+	}
+
+	return v[:i], nil
+}
+
+// ValidFence returns false if a newer lease was granted for the channel after token
+func (r RedisDB) ValidFence(chanID string, token int64) (bool, error) {
+	defer dbValidFenceTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	latest, err := redis.Int64(conn.Do("GET", fenceKey+chanID))
+	if err == redis.ErrNil {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return token >= latest, nil
+}
+
+// Publish sends data to the inbox of a bot instance
+func (r RedisDB) Publish(instanceID string, data []byte) error {
+	defer dbPublishTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("PUBLISH", inboxKey+instanceID, data)
+
+	return err
+}
+
+// Subscribe receives data published to the inbox of a bot instance. The returned
+// channel is closed when ctx is done or the connection failed
+func (r RedisDB) Subscribe(ctx context.Context, instanceID string) (<-chan []byte, error) {
+	psc := redis.PubSubConn{Conn: r.pool.Get()}
+	if err := psc.Subscribe(inboxKey + instanceID); err != nil {
+		psc.Close()
+		return nil, err
+	}
+
+	out := make(chan []byte, 100)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		// unblock Receive
+		psc.Close()
+	}()
+	go func() {
+		defer close(out)
+		defer close(done)
+		for {
+			switch v := psc.Receive().(type) {
+			case redis.Message:
+				select {
+				case out <- v.Data:
+				case <-ctx.Done():
+					return
+				}
+			case error:
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
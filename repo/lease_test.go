@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLeaseTakeover(t *testing.T) {
+	// two bot instances sharing one redis
+	a, b := new(RedisDB), new(RedisDB)
+	if err := a.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	chanID, ttl := "lease", 100*time.Millisecond
+	tokenA, err := a.AcquireLease(chanID, "a", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenA == 0 {
+		t.Fatal("a should acquire the lease")
+	}
+	if token, err := b.AcquireLease(chanID, "b", ttl); err != nil || token != 0 {
+		t.Fatalf("b should not acquire the lease, token %d err %v", token, err)
+	}
+	if owner, err := b.LeaseOwner(chanID); err != nil || owner != "a" {
+		t.Errorf("owner want a got %q err %v", owner, err)
+	}
+	if ok, err := a.RenewLease(chanID, "a", tokenA, ttl); err != nil || !ok {
+		t.Errorf("a should renew the lease, err %v", err)
+	}
+
+	// a died, b takes over once the lease expired
+	time.Sleep(2 * ttl)
+	tokenB, err := b.AcquireLease(chanID, "b", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenB <= tokenA {
+		t.Fatalf("token should increase, a %d b %d", tokenA, tokenB)
+	}
+	if ok, _ := a.RenewLease(chanID, "a", tokenA, ttl); ok {
+		t.Errorf("a should have lost the lease")
+	}
+	if ok, _ := a.ValidFence(chanID, tokenA); ok {
+		t.Errorf("token of a should be fenced")
+	}
+	if ok, _ := b.ValidFence(chanID, tokenB); !ok {
+		t.Errorf("token of b should be valid")
+	}
+
+	// a can not release the lease of b
+	if err := a.ReleaseLease(chanID, "a", tokenA); err != nil {
+		t.Fatal(err)
+	}
+	if owner, _ := a.LeaseOwner(chanID); owner != "b" {
+		t.Errorf("owner want b got %q", owner)
+	}
+	if err := b.ReleaseLease(chanID, "b", tokenB); err != nil {
+		t.Fatal(err)
+	}
+	if owner, _ := a.LeaseOwner(chanID); owner != "" {
+		t.Errorf("lease should be released, owner %q", owner)
+	}
+}
+
+func TestInbox(t *testing.T) {
+	a, b := new(RedisDB), new(RedisDB)
+	if err := a.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inbox, err := b.Subscribe(ctx, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Publish("b", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case data := <-inbox:
+		if want, got := "hello", string(data); want != got {
+			t.Errorf("data want %s got %s", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+}
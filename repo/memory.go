@@ -1,6 +1,10 @@
 package repo
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/yulrizka/fam100/model"
 )
 
@@ -8,11 +12,38 @@ import (
 type MemoryDB struct {
 	Seed   int64
 	played int
+
+	mu          sync.Mutex
+	checkpoints map[string]map[string][]byte
+	schedules   map[string]map[string]string
+	tournaments map[string][]byte
+	results     map[string]map[string]int64
+	dailyDone   map[string]map[model.PlayerID]bool
+	stats       map[model.PlayerID]map[string]int64
+	streaks     map[model.PlayerID]streak
+	badges      map[model.PlayerID]map[string]time.Time
+	records     map[string]model.PlayerStats
+	ratings     map[model.PlayerID]model.Rating
+	season      int
+}
+
+type streak struct {
+	day    string
+	streak int64
 }
 
 func (m *MemoryDB) Reset() error      { return nil }
 func (m *MemoryDB) Init() (err error) { return nil }
-func (m *MemoryDB) ChannelRanking(chanID string, limit int) (ranking model.Rank, err error) {
+func (m *MemoryDB) ChannelRanking(chanID string, period Period, limit int) (ranking model.Rank, err error) {
+	return nil, nil
+}
+func (m *MemoryDB) ChannelRankingAt(chanID string, period Period, t time.Time, limit int) (model.Rank, error) {
+	return nil, nil
+}
+func (m *MemoryDB) ChannelRankingPage(chanID string, period Period, offset, limit int) (model.Rank, error) {
+	return nil, nil
+}
+func (m *MemoryDB) ChannelRankingAround(chanID string, period Period, playerID model.PlayerID, n int) (model.Rank, error) {
 	return nil, nil
 }
 func (m *MemoryDB) ChannelCount() (total int, err error)                           { return 0, nil }
@@ -22,12 +53,12 @@ func (m *MemoryDB) GlobalConfig(key, defaultValue string) (string, error)
 func (m *MemoryDB) PlayerCount() (total int, err error)                            { return 0, nil }
 func (m *MemoryDB) IncStats(key string) error                                      { return nil }
 func (m *MemoryDB) IncChannelStats(chanID, key string) error                       { return nil }
-func (m *MemoryDB) IncPlayerStats(playerID model.PlayerID, key string) error       { return nil }
 func (m *MemoryDB) Stats(key string) (interface{}, error)                          { return nil, nil }
 func (m *MemoryDB) ChannelStats(chanID, key string) (interface{}, error)           { return nil, nil }
-func (m *MemoryDB) PlayerStats(playerID, key string) (interface{}, error)          { return nil, nil }
+func (m *MemoryDB) IncQuestionStats(questionID int, key string) error              { return nil }
+func (m *MemoryDB) QuestionStats(questionID int) (map[string]int, error)           { return nil, nil }
 func (m *MemoryDB) SaveScore(chanID, chanName string, scores model.Rank) error     { return nil }
-func (m *MemoryDB) PlayerRanking(limit int) (model.Rank, error)                    { return nil, nil }
+func (m *MemoryDB) PlayerRanking(period Period, limit int) (model.Rank, error)     { return nil, nil }
 func (m *MemoryDB) PlayerScore(playerID model.PlayerID) (ps model.PlayerScore, err error) {
 	return model.PlayerScore{}, nil
 }
@@ -42,3 +73,343 @@ func (m *MemoryDB) IncRoundPlayed(chanID string) error {
 	m.played++
 	return nil
 }
+
+func (m *MemoryDB) SaveCheckpoint(kind, chanID string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.checkpoints == nil {
+		m.checkpoints = make(map[string]map[string][]byte)
+	}
+	if m.checkpoints[kind] == nil {
+		m.checkpoints[kind] = make(map[string][]byte)
+	}
+	m.checkpoints[kind][chanID] = data
+	return nil
+}
+func (m *MemoryDB) DeleteCheckpoint(kind, chanID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.checkpoints[kind], chanID)
+	return nil
+}
+func (m *MemoryDB) Checkpoints(kind string) (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	checkpoints := make(map[string][]byte)
+	for chanID, data := range m.checkpoints[kind] {
+		checkpoints[chanID] = data
+	}
+	return checkpoints, nil
+}
+func (m *MemoryDB) CheckpointChannels(kind string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	channels := make([]string, 0, len(m.checkpoints[kind]))
+	for chanID := range m.checkpoints[kind] {
+		channels = append(channels, chanID)
+	}
+	return channels, nil
+}
+func (m *MemoryDB) Checkpoint(kind, chanID string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkpoints[kind][chanID], nil
+}
+
+// MemoryDB runs as a single instance, it always owns the lease
+func (m *MemoryDB) AcquireLease(chanID, instanceID string, ttl time.Duration) (int64, error) {
+	return 1, nil
+}
+func (m *MemoryDB) RenewLease(chanID, instanceID string, token int64, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+func (m *MemoryDB) ReleaseLease(chanID, instanceID string, token int64) error { return nil }
+func (m *MemoryDB) LeaseOwner(chanID string) (string, error)                  { return "", nil }
+func (m *MemoryDB) ValidFence(chanID string, token int64) (bool, error)       { return true, nil }
+func (m *MemoryDB) Publish(instanceID string, data []byte) error              { return nil }
+func (m *MemoryDB) Subscribe(ctx context.Context, instanceID string) (<-chan []byte, error) {
+	return nil, nil
+}
+func (m *MemoryDB) AddWebhookDelivery(data []byte, keep int) error { return nil }
+func (m *MemoryDB) WebhookDeliveries(limit int) ([][]byte, error)  { return nil, nil }
+func (m *MemoryDB) FlagPlayer(chanID string, playerID model.PlayerID, reason string) error {
+	return nil
+}
+func (m *MemoryDB) FlaggedPlayers(chanID string) (map[model.PlayerID]map[string]int, error) {
+	return nil, nil
+}
+func (m *MemoryDB) SetChannelConfig(chanID, key, value string) error { return nil }
+func (m *MemoryDB) ChannelRankMode(chanID string) model.RankMode     { return model.StandardRank }
+
+func (m *MemoryDB) AddSchedule(chanID, chanName, spec string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.schedules == nil {
+		m.schedules = make(map[string]map[string]string)
+	}
+	if m.schedules[chanID] == nil {
+		m.schedules[chanID] = make(map[string]string)
+	}
+	m.schedules[chanID][spec] = chanName
+	return nil
+}
+
+func (m *MemoryDB) RemoveSchedule(chanID, spec string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.schedules[chanID], spec)
+	if len(m.schedules[chanID]) == 0 {
+		delete(m.schedules, chanID)
+	}
+	return nil
+}
+
+func (m *MemoryDB) Schedules() (map[string]map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedules := make(map[string]map[string]string, len(m.schedules))
+	for chanID, specs := range m.schedules {
+		schedules[chanID] = make(map[string]string, len(specs))
+		for spec, name := range specs {
+			schedules[chanID][spec] = name
+		}
+	}
+	return schedules, nil
+}
+
+func (m *MemoryDB) SavePracticeScore(ps model.PlayerScore) (bool, error) { return false, nil }
+func (m *MemoryDB) PracticeRanking(limit int) (model.Rank, error)        { return nil, nil }
+func (m *MemoryDB) PracticeScore(playerID model.PlayerID) (model.PlayerScore, error) {
+	return model.PlayerScore{}, nil
+}
+
+func (m *MemoryDB) SaveTournament(id string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.tournaments == nil {
+		m.tournaments = make(map[string][]byte)
+	}
+	m.tournaments[id] = data
+	return nil
+}
+
+func (m *MemoryDB) DeleteTournament(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.tournaments, id)
+	delete(m.results, id)
+	return nil
+}
+
+func (m *MemoryDB) Tournaments() (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tournaments := make(map[string][]byte, len(m.tournaments))
+	for id, data := range m.tournaments {
+		tournaments[id] = data
+	}
+	return tournaments, nil
+}
+
+func (m *MemoryDB) Tournament(id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.tournaments[id], nil
+}
+
+func (m *MemoryDB) SetTournamentResult(id, key string, value int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.results == nil {
+		m.results = make(map[string]map[string]int64)
+	}
+	if m.results[id] == nil {
+		m.results[id] = make(map[string]int64)
+	}
+	m.results[id][key] = value
+	return nil
+}
+
+func (m *MemoryDB) TournamentResults(id string) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make(map[string]int64, len(m.results[id]))
+	for k, v := range m.results[id] {
+		results[k] = v
+	}
+	return results, nil
+}
+
+func (m *MemoryDB) SaveDailyScore(day string, ps model.PlayerScore) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dailyDone == nil {
+		m.dailyDone = make(map[string]map[model.PlayerID]bool)
+	}
+	if m.dailyDone[day] == nil {
+		m.dailyDone[day] = make(map[model.PlayerID]bool)
+	}
+	if m.dailyDone[day][ps.PlayerID] {
+		return false, nil
+	}
+	m.dailyDone[day][ps.PlayerID] = true
+	return true, nil
+}
+
+func (m *MemoryDB) DailyCompleted(day string, playerID model.PlayerID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.dailyDone[day][playerID], nil
+}
+
+func (m *MemoryDB) DailyRanking(day string, limit int) (model.Rank, error) { return nil, nil }
+func (m *MemoryDB) DailyTotalRanking(limit int) (model.Rank, error)        { return nil, nil }
+
+func (m *MemoryDB) IncPlayerStats(playerID model.PlayerID, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stats == nil {
+		m.stats = make(map[model.PlayerID]map[string]int64)
+	}
+	if m.stats[playerID] == nil {
+		m.stats[playerID] = make(map[string]int64)
+	}
+	m.stats[playerID][key]++
+	return m.stats[playerID][key], nil
+}
+
+func (m *MemoryDB) PlayStreak(playerID model.PlayerID, day, yesterday string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.streaks == nil {
+		m.streaks = make(map[model.PlayerID]streak)
+	}
+	s := m.streaks[playerID]
+	switch s.day {
+	case day:
+		return s.streak, nil
+	case yesterday:
+		s.streak++
+	default:
+		s.streak = 1
+	}
+	s.day = day
+	m.streaks[playerID] = s
+	return s.streak, nil
+}
+
+func (m *MemoryDB) AddBadge(playerID model.PlayerID, badge string, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.badges == nil {
+		m.badges = make(map[model.PlayerID]map[string]time.Time)
+	}
+	if m.badges[playerID] == nil {
+		m.badges[playerID] = make(map[string]time.Time)
+	}
+	if _, ok := m.badges[playerID][badge]; ok {
+		return false, nil
+	}
+	m.badges[playerID][badge] = at
+	return true, nil
+}
+
+func (m *MemoryDB) Badges(playerID model.PlayerID) (map[string]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	badges := make(map[string]time.Time, len(m.badges[playerID]))
+	for badge, at := range m.badges[playerID] {
+		badges[badge] = at
+	}
+	return badges, nil
+}
+
+func (m *MemoryDB) SavePlayerStats(chanID string, playerID model.PlayerID, game model.PlayerStats) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.records == nil {
+		m.records = make(map[string]model.PlayerStats)
+	}
+	for _, key := range []string{chanID + "_" + string(playerID), string(playerID)} {
+		m.records[key] = m.records[key].Add(game)
+	}
+	return nil
+}
+
+func (m *MemoryDB) PlayerStats(chanID string, playerID model.PlayerID) (model.PlayerStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := string(playerID)
+	if chanID != "" {
+		key = chanID + "_" + key
+	}
+	return m.records[key], nil
+}
+
+func (m *MemoryDB) Ratings(playerIDs []model.PlayerID) (map[model.PlayerID]model.Rating, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ratings := make(map[model.PlayerID]model.Rating)
+	for _, id := range playerIDs {
+		if r, ok := m.ratings[id]; ok {
+			ratings[id] = r
+		}
+	}
+	return ratings, nil
+}
+
+func (m *MemoryDB) UpdateRatings(playerIDs []model.PlayerID, minGames int, update func(map[model.PlayerID]model.Rating) map[model.PlayerID]model.Rating) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := make(map[model.PlayerID]model.Rating)
+	for _, id := range playerIDs {
+		if r, ok := m.ratings[id]; ok {
+			current[id] = r
+		}
+	}
+	if m.ratings == nil {
+		m.ratings = make(map[model.PlayerID]model.Rating)
+	}
+	for id, r := range update(current) {
+		m.ratings[id] = r
+	}
+	return nil
+}
+
+func (m *MemoryDB) RatingRanking(limit int) (model.Rank, error) { return nil, nil }
+
+func (m *MemoryDB) Season() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.season + 1, nil
+}
+
+// StartSeason only counts the seasons, MemoryDB does not keep rankings to archive
+func (m *MemoryDB) StartSeason() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.season++
+	return m.season, nil
+}
+func (m *MemoryDB) SeasonRanking(chanID string, season, limit int) (model.Rank, error) {
+	return nil, nil
+}
@@ -19,4 +19,71 @@ var (
 	dbSaveScoreTimer       = metrics.NewRegisteredTimer("db.SaveScore.ns", metrics.DefaultRegistry)
 	dbGetRankingTimer      = metrics.NewRegisteredTimer("db.getRanking.ns", metrics.DefaultRegistry)
 	dbGetScoreTimer        = metrics.NewRegisteredTimer("db.getScore.ns", metrics.DefaultRegistry)
+
+	// ranking window metrics
+	dbChannelRankingAroundTimer = metrics.NewRegisteredTimer("db.ChannelRankingAround.ns", metrics.DefaultRegistry)
+
+	// question metrics
+	dbIncQuestionStatsTimer = metrics.NewRegisteredTimer("db.IncQuestionStats.ns", metrics.DefaultRegistry)
+	dbQuestionStatsTimer    = metrics.NewRegisteredTimer("db.QuestionStats.ns", metrics.DefaultRegistry)
+
+	// checkpoint metrics
+	dbSaveCheckpointTimer     = metrics.NewRegisteredTimer("db.SaveCheckpoint.ns", metrics.DefaultRegistry)
+	dbDeleteCheckpointTimer   = metrics.NewRegisteredTimer("db.DeleteCheckpoint.ns", metrics.DefaultRegistry)
+	dbCheckpointsTimer        = metrics.NewRegisteredTimer("db.Checkpoints.ns", metrics.DefaultRegistry)
+	dbCheckpointTimer         = metrics.NewRegisteredTimer("db.Checkpoint.ns", metrics.DefaultRegistry)
+	dbCheckpointChannelsTimer = metrics.NewRegisteredTimer("db.CheckpointChannels.ns", metrics.DefaultRegistry)
+
+	// lease metrics
+	dbAcquireLeaseTimer = metrics.NewRegisteredTimer("db.AcquireLease.ns", metrics.DefaultRegistry)
+	dbRenewLeaseTimer   = metrics.NewRegisteredTimer("db.RenewLease.ns", metrics.DefaultRegistry)
+	dbReleaseLeaseTimer = metrics.NewRegisteredTimer("db.ReleaseLease.ns", metrics.DefaultRegistry)
+	dbLeaseOwnerTimer   = metrics.NewRegisteredTimer("db.LeaseOwner.ns", metrics.DefaultRegistry)
+	dbValidFenceTimer   = metrics.NewRegisteredTimer("db.ValidFence.ns", metrics.DefaultRegistry)
+	dbPublishTimer      = metrics.NewRegisteredTimer("db.Publish.ns", metrics.DefaultRegistry)
+
+	// flag metrics
+	dbFlagPlayerTimer     = metrics.NewRegisteredTimer("db.FlagPlayer.ns", metrics.DefaultRegistry)
+	dbFlaggedPlayersTimer = metrics.NewRegisteredTimer("db.FlaggedPlayers.ns", metrics.DefaultRegistry)
+
+	// webhook metrics
+	dbAddWebhookDeliveryTimer = metrics.NewRegisteredTimer("db.AddWebhookDelivery.ns", metrics.DefaultRegistry)
+	dbWebhookDeliveriesTimer  = metrics.NewRegisteredTimer("db.WebhookDeliveries.ns", metrics.DefaultRegistry)
+
+	// schedule metrics
+	dbSetChannelConfigTimer = metrics.NewRegisteredTimer("db.SetChannelConfig.ns", metrics.DefaultRegistry)
+	dbAddScheduleTimer      = metrics.NewRegisteredTimer("db.AddSchedule.ns", metrics.DefaultRegistry)
+	dbRemoveScheduleTimer   = metrics.NewRegisteredTimer("db.RemoveSchedule.ns", metrics.DefaultRegistry)
+	dbSchedulesTimer        = metrics.NewRegisteredTimer("db.Schedules.ns", metrics.DefaultRegistry)
+
+	// practice metrics
+	dbSavePracticeScoreTimer = metrics.NewRegisteredTimer("db.SavePracticeScore.ns", metrics.DefaultRegistry)
+
+	// tournament metrics
+	dbSaveTournamentTimer      = metrics.NewRegisteredTimer("db.SaveTournament.ns", metrics.DefaultRegistry)
+	dbDeleteTournamentTimer    = metrics.NewRegisteredTimer("db.DeleteTournament.ns", metrics.DefaultRegistry)
+	dbTournamentsTimer         = metrics.NewRegisteredTimer("db.Tournaments.ns", metrics.DefaultRegistry)
+	dbTournamentTimer          = metrics.NewRegisteredTimer("db.Tournament.ns", metrics.DefaultRegistry)
+	dbSetTournamentResultTimer = metrics.NewRegisteredTimer("db.SetTournamentResult.ns", metrics.DefaultRegistry)
+	dbTournamentResultsTimer   = metrics.NewRegisteredTimer("db.TournamentResults.ns", metrics.DefaultRegistry)
+
+	// daily metrics
+	dbSaveDailyScoreTimer = metrics.NewRegisteredTimer("db.SaveDailyScore.ns", metrics.DefaultRegistry)
+	dbDailyCompletedTimer = metrics.NewRegisteredTimer("db.DailyCompleted.ns", metrics.DefaultRegistry)
+
+	// achievement metrics
+	dbPlayStreakTimer = metrics.NewRegisteredTimer("db.PlayStreak.ns", metrics.DefaultRegistry)
+	dbAddBadgeTimer   = metrics.NewRegisteredTimer("db.AddBadge.ns", metrics.DefaultRegistry)
+	dbBadgesTimer     = metrics.NewRegisteredTimer("db.Badges.ns", metrics.DefaultRegistry)
+
+	// player record metrics
+	dbSavePlayerStatsTimer = metrics.NewRegisteredTimer("db.SavePlayerStats.ns", metrics.DefaultRegistry)
+
+	// rating metrics
+	dbRatingsTimer       = metrics.NewRegisteredTimer("db.Ratings.ns", metrics.DefaultRegistry)
+	dbUpdateRatingsTimer = metrics.NewRegisteredTimer("db.UpdateRatings.ns", metrics.DefaultRegistry)
+
+	// season metrics
+	dbSeasonTimer      = metrics.NewRegisteredTimer("db.Season.ns", metrics.DefaultRegistry)
+	dbStartSeasonTimer = metrics.NewRegisteredTimer("db.StartSeason.ns", metrics.DefaultRegistry)
 )
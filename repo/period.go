@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Period of a leaderboard. Scores are added to the bucket of each period that
+// contains the time the score was saved, PeriodAll has a single bucket
+type Period string
+
+// Available Period
+const (
+	PeriodAll   Period = "all"
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week" // ISO week, starts on monday
+	PeriodMonth Period = "month"
+)
+
+// Periods are the periods that have time buckets
+var Periods = []Period{PeriodDay, PeriodWeek, PeriodMonth}
+
+// Location is the time zone of the bucket boundaries
+var Location = time.Local
+
+// PeriodRetention is how long a bucket is kept after it ended
+var PeriodRetention = map[Period]time.Duration{
+	PeriodDay:   31 * 24 * time.Hour,
+	PeriodWeek:  13 * 7 * 24 * time.Hour,
+	PeriodMonth: 366 * 24 * time.Hour,
+}
+
+// ParsePeriod returns the period of the name
+func ParsePeriod(name string) (Period, error) {
+	switch p := Period(name); p {
+	case PeriodAll, PeriodDay, PeriodWeek, PeriodMonth:
+		return p, nil
+	}
+
+	return "", errors.Errorf("unknown period %q", name)
+}
+
+// Bounds returns the start and the end of the bucket of the period that contains t
+func (p Period) Bounds(t time.Time) (start, end time.Time) {
+	t = t.In(Location)
+	y, m, d := t.Date()
+	switch p {
+	case PeriodDay:
+		start = time.Date(y, m, d, 0, 0, 0, 0, Location)
+		return start, start.AddDate(0, 0, 1)
+	case PeriodWeek:
+		// monday is the first day of the week
+		offset := (int(t.Weekday()) + 6) % 7
+		start = time.Date(y, m, d-offset, 0, 0, 0, 0, Location)
+		return start, start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		start = time.Date(y, m, 1, 0, 0, 0, 0, Location)
+		return start, start.AddDate(0, 1, 0)
+	}
+
+	return time.Time{}, time.Time{}
+}
+
+// Bucket returns the name of the bucket of the period that contains t, empty for PeriodAll
+func (p Period) Bucket(t time.Time) string {
+	start, _ := p.Bounds(t)
+	switch p {
+	case PeriodDay:
+		return start.Format("2006-01-02")
+	case PeriodWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case PeriodMonth:
+		return start.Format("2006-01")
+	}
+
+	return ""
+}
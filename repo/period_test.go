@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodBucket(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer func(l *time.Location) { Location = l }(Location)
+	Location = loc
+
+	// sunday in UTC, monday in jakarta
+	at := time.Date(2017, 1, 1, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		period Period
+		bucket string
+		start  time.Time
+		end    time.Time
+	}{
+		{PeriodDay, "2017-01-02", time.Date(2017, 1, 2, 0, 0, 0, 0, loc), time.Date(2017, 1, 3, 0, 0, 0, 0, loc)},
+		{PeriodWeek, "2017-W01", time.Date(2017, 1, 2, 0, 0, 0, 0, loc), time.Date(2017, 1, 9, 0, 0, 0, 0, loc)},
+		{PeriodMonth, "2017-01", time.Date(2017, 1, 1, 0, 0, 0, 0, loc), time.Date(2017, 2, 1, 0, 0, 0, 0, loc)},
+		{PeriodAll, "", time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		if want, got := tt.bucket, tt.period.Bucket(at); want != got {
+			t.Errorf("%s bucket, want %s got %s", tt.period, want, got)
+		}
+		start, end := tt.period.Bounds(at)
+		if !tt.start.Equal(start) || !tt.end.Equal(end) {
+			t.Errorf("%s bounds, want %s - %s got %s - %s", tt.period, tt.start, tt.end, start, end)
+		}
+	}
+
+	// the week of 2016-01-03 (sunday) belongs to the last ISO week of 2015
+	if want, got := "2015-W53", PeriodWeek.Bucket(time.Date(2016, 1, 3, 12, 0, 0, 0, loc)); want != got {
+		t.Errorf("week bucket, want %s got %s", want, got)
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	if p, err := ParsePeriod("week"); err != nil || p != PeriodWeek {
+		t.Errorf("parse week, got %s %v", p, err)
+	}
+	if _, err := ParsePeriod("year"); err == nil {
+		t.Errorf("parse year should fail")
+	}
+}
@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/pkg/errors"
+	"github.com/yulrizka/fam100/model"
+)
+
+// Practice games are played alone in a private chat. The practice ranking keeps
+// the best game score of each player and never mixes with channel or player ranking.
+
+var bestScoreScript = redis.NewScript(1, `
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if score and tonumber(score) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return 1
+`)
+
+// SavePracticeScore saves the score of a practice game, it returns true if it is the best score of the player
+func (r RedisDB) SavePracticeScore(ps model.PlayerScore) (best bool, err error) {
+	defer dbSavePracticeScoreTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("HSET", pNameKey, ps.PlayerID, ps.Name); err != nil {
+		return false, errors.Wrap(err, "failed to execute command")
+	}
+
+	return redis.Bool(bestScoreScript.Do(conn, practiceRankKey, ps.PlayerID, ps.Score))
+}
+
+// PracticeRanking returns players with the best practice score
+func (r RedisDB) PracticeRanking(limit int) (model.Rank, error) {
+	return r.getRanking(practiceRankKey, limit-1, model.StandardRank)
+}
+
+// PracticeScore returns the best practice score of a player
+func (r RedisDB) PracticeScore(playerID model.PlayerID) (model.PlayerScore, error) {
+	return r.getScore(practiceRankKey, playerID)
+}
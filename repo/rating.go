@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/pkg/errors"
+	"github.com/yulrizka/fam100/model"
+)
+
+// ratingRetries is the number of attempts of UpdateRatings when the ratings are
+// modified by another game at the same time
+var ratingRetries = 10
+
+// Ratings returns the rating of the players, players that were never rated are not in the result
+func (r RedisDB) Ratings(playerIDs []model.PlayerID) (map[model.PlayerID]model.Rating, error) {
+	defer dbRatingsTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return ratings(conn, playerIDs)
+}
+
+func ratings(conn redis.Conn, playerIDs []model.PlayerID) (map[model.PlayerID]model.Rating, error) {
+	ratings := make(map[model.PlayerID]model.Rating, len(playerIDs))
+	if len(playerIDs) == 0 {
+		return ratings, nil
+	}
+
+	args := make([]interface{}, 0, len(playerIDs)+1)
+	args = append(args, ratingKey)
+	for _, id := range playerIDs {
+		args = append(args, id)
+	}
+	values, err := redis.ByteSlices(conn.Do("HMGET", args...))
+	if err != nil {
+		return nil, err
+	}
+	for i, data := range values {
+		if data == nil {
+			continue
+		}
+		var rating model.Rating
+		if err := json.Unmarshal(data, &rating); err != nil {
+			return nil, err
+		}
+		ratings[playerIDs[i]] = rating
+	}
+
+	return ratings, nil
+}
+
+// UpdateRatings stores the ratings returned by update from the current ratings of the players.
+// The ratings are watched so a concurrent update retries with the new ratings instead of
+// being overwritten. A player enters the rating ranking after minGames games
+func (r RedisDB) UpdateRatings(playerIDs []model.PlayerID, minGames int, update func(map[model.PlayerID]model.Rating) map[model.PlayerID]model.Rating) error {
+	defer dbUpdateRatingsTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	for i := 0; i < ratingRetries; i++ {
+		if _, err := conn.Do("WATCH", ratingKey); err != nil {
+			return errors.Wrap(err, "failed to watch ratings")
+		}
+		current, err := ratings(conn, playerIDs)
+		if err != nil {
+			conn.Do("UNWATCH")
+			return errors.Wrap(err, "failed to get ratings")
+		}
+
+		if err := conn.Send("MULTI"); err != nil {
+			return errors.Wrap(err, "failed to execute command")
+		}
+		for id, rating := range update(current) {
+			data, err := json.Marshal(rating)
+			if err != nil {
+				conn.Do("DISCARD")
+				return errors.Wrap(err, "failed to encode rating")
+			}
+			if err := conn.Send("HSET", ratingKey, id, data); err != nil {
+				return errors.Wrap(err, "failed to execute command")
+			}
+			if rating.Games < minGames {
+				continue
+			}
+			// ranking scores are integer
+			if err := conn.Send("ZADD", ratingRankKey, int64(math.Round(rating.Value)), id); err != nil {
+				return errors.Wrap(err, "failed to execute command")
+			}
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return errors.Wrap(err, "failed to save ratings")
+		}
+		// nil reply when the ratings changed since WATCH
+		if reply != nil {
+			return nil
+		}
+	}
+
+	return errors.Errorf("ratings changed during %d attempts", ratingRetries)
+}
+
+// RatingRanking returns players with the highest rating
+func (r RedisDB) RatingRanking(limit int) (model.Rank, error) {
+	return r.getRanking(ratingRankKey, limit-1, model.StandardRank)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"hash/crc32"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/garyburd/redigo/redis"
@@ -18,6 +19,7 @@ func SetRedisPrefix(prefix string) {
 	gStatsKey = fmt.Sprintf("%s_stats_", RedisPrefix)
 	cStatsKey = fmt.Sprintf("%s_chan_stats_", RedisPrefix)
 	pStatsKey = fmt.Sprintf("%s_player_stats_", RedisPrefix)
+	qStatsKey = fmt.Sprintf("%s_question_stats_", RedisPrefix)
 	cRankKey = fmt.Sprintf("%s_chan_rank_", RedisPrefix)
 
 	cNameKey = fmt.Sprintf("%s_chan_name", RedisPrefix)
@@ -26,6 +28,32 @@ func SetRedisPrefix(prefix string) {
 
 	cConfigKey = fmt.Sprintf("%s_chan_config_", RedisPrefix)
 	gConfigKey = fmt.Sprintf("%s_config", RedisPrefix)
+	checkpointKey = fmt.Sprintf("%s_checkpoint_", RedisPrefix)
+
+	leaseKey = fmt.Sprintf("%s_lease_", RedisPrefix)
+	leaseTokenKey = fmt.Sprintf("%s_lease_token", RedisPrefix)
+	fenceKey = fmt.Sprintf("%s_fence_", RedisPrefix)
+	inboxKey = fmt.Sprintf("%s_inbox_", RedisPrefix)
+	webhookLogKey = fmt.Sprintf("%s_webhook_log", RedisPrefix)
+	cFlagKey = fmt.Sprintf("%s_chan_flag_", RedisPrefix)
+	scheduleKey = fmt.Sprintf("%s_schedule_", RedisPrefix)
+	scheduleChanKey = fmt.Sprintf("%s_schedule_chans", RedisPrefix)
+	practiceRankKey = fmt.Sprintf("%s_practice_rank", RedisPrefix)
+	tournamentKey = fmt.Sprintf("%s_tournament", RedisPrefix)
+	tournamentResultKey = fmt.Sprintf("%s_tournament_result_", RedisPrefix)
+	dailyDoneKey = fmt.Sprintf("%s_daily_done_", RedisPrefix)
+	dailyRankKey = fmt.Sprintf("%s_daily_rank_", RedisPrefix)
+	dailyTotalKey = fmt.Sprintf("%s_daily_total_rank", RedisPrefix)
+	streakKey = fmt.Sprintf("%s_player_streak_", RedisPrefix)
+	badgeKey = fmt.Sprintf("%s_player_badge_", RedisPrefix)
+	pRecordKey = fmt.Sprintf("%s_player_record_", RedisPrefix)
+	ratingKey = fmt.Sprintf("%s_player_rating", RedisPrefix)
+	ratingRankKey = fmt.Sprintf("%s_player_rating_rank", RedisPrefix)
+	cPeriodRankKey = fmt.Sprintf("%s_period_chan_rank_", RedisPrefix)
+	pPeriodRankKey = fmt.Sprintf("%s_period_player_rank_", RedisPrefix)
+	seasonKey = fmt.Sprintf("%s_season", RedisPrefix)
+	cSeasonRankKey = fmt.Sprintf("%s_season_chan_rank_", RedisPrefix)
+	pSeasonRankKey = fmt.Sprintf("%s_season_player_rank_", RedisPrefix)
 }
 
 type RedisDB struct {
@@ -109,6 +137,24 @@ func (r *RedisDB) ChannelConfig(chanID, key, defaultValue string) (config string
 	return config, nil
 }
 
+// SetChannelConfig sets a channel config, empty value removes it
+func (r *RedisDB) SetChannelConfig(chanID, key, value string) error {
+	defer dbSetChannelConfigTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	rkey := fmt.Sprintf("%s%s", cConfigKey, chanID)
+	var err error
+	if value == "" {
+		_, err = conn.Do("HDEL", rkey, key)
+	} else {
+		_, err = conn.Do("HSET", rkey, key, value)
+	}
+
+	return err
+}
+
 func (r *RedisDB) GlobalConfig(key, defaultValue string) (config string, err error) {
 	defer dbGlobalConfigTimer.UpdateSince(time.Now())
 
@@ -177,16 +223,15 @@ func (r RedisDB) IncChannelStats(chanID, key string) error {
 	return err
 }
 
-func (r RedisDB) IncPlayerStats(playerID model.PlayerID, key string) error {
+// IncPlayerStats increments statistic of a player and returns the new value
+func (r RedisDB) IncPlayerStats(playerID model.PlayerID, key string) (int64, error) {
 	defer dbIncPlayerStatsTimer.UpdateSince(time.Now())
 
 	conn := r.pool.Get()
 	defer conn.Close()
 
 	rkey := fmt.Sprintf("%s%s_%s", pStatsKey, key, playerID)
-	_, err := conn.Do("INCR", rkey)
-
-	return err
+	return redis.Int64(conn.Do("INCR", rkey))
 }
 
 func (r RedisDB) Stats(key string) (interface{}, error) {
@@ -209,14 +254,310 @@ func (r RedisDB) ChannelStats(chanID, key string) (interface{}, error) {
 	return conn.Do("GET", rkey)
 }
 
-func (r RedisDB) PlayerStats(playerID, key string) (interface{}, error) {
-	defer dbPlayerStatsTimer.UpdateSince(time.Now())
+// IncQuestionStats increments statistic such as played or skipped of a question
+func (r RedisDB) IncQuestionStats(questionID int, key string) error {
+	defer dbIncQuestionStatsTimer.UpdateSince(time.Now())
 
 	conn := r.pool.Get()
 	defer conn.Close()
 
-	rkey := fmt.Sprintf("%s%s_%s", pStatsKey, key, playerID)
-	return conn.Do("GET", rkey)
+	rkey := fmt.Sprintf("%s%d", qStatsKey, questionID)
+	_, err := conn.Do("HINCRBY", rkey, key, 1)
+
+	return err
+}
+
+// QuestionStats returns all statistic of a question
+func (r RedisDB) QuestionStats(questionID int) (map[string]int, error) {
+	defer dbQuestionStatsTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	rkey := fmt.Sprintf("%s%d", qStatsKey, questionID)
+	return redis.IntMap(conn.Do("HGETALL", rkey))
+}
+
+// SaveCheckpoint stores the state of a running game or lobby of a channel
+func (r RedisDB) SaveCheckpoint(kind, chanID string, data []byte) error {
+	defer dbSaveCheckpointTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HSET", checkpointKey+kind, chanID, data)
+
+	return err
+}
+
+// DeleteCheckpoint removes the checkpoint of a channel
+func (r RedisDB) DeleteCheckpoint(kind, chanID string) error {
+	defer dbDeleteCheckpointTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HDEL", checkpointKey+kind, chanID)
+
+	return err
+}
+
+// Checkpoints returns all checkpoint of a kind indexed by channel ID
+func (r RedisDB) Checkpoints(kind string) (map[string][]byte, error) {
+	defer dbCheckpointsTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.ByteSlices(conn.Do("HGETALL", checkpointKey+kind))
+	if err != nil {
+		return nil, err
+	}
+	if len(values)%2 != 0 {
+		return nil, errors.New("expecting even number of values")
+	}
+
+	checkpoints := make(map[string][]byte, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		checkpoints[string(values[i])] = values[i+1]
+	}
+
+	return checkpoints, nil
+}
+
+// CheckpointChannels returns the channels that have a checkpoint of a kind
+func (r RedisDB) CheckpointChannels(kind string) ([]string, error) {
+	defer dbCheckpointChannelsTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Strings(conn.Do("HKEYS", checkpointKey+kind))
+}
+
+// Checkpoint returns the checkpoint of a channel, nil if there is none
+func (r RedisDB) Checkpoint(kind, chanID string) ([]byte, error) {
+	defer dbCheckpointTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("HGET", checkpointKey+kind, chanID))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// FlagPlayer increments the number of times a player was flagged in a channel for the reason
+func (r RedisDB) FlagPlayer(chanID string, playerID model.PlayerID, reason string) error {
+	defer dbFlagPlayerTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HINCRBY", cFlagKey+chanID, fmt.Sprintf("%s:%s", playerID, reason), 1)
+
+	return err
+}
+
+// FlaggedPlayers returns the number of times each player was flagged in a channel by reason
+func (r RedisDB) FlaggedPlayers(chanID string) (map[model.PlayerID]map[string]int, error) {
+	defer dbFlaggedPlayersTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.IntMap(conn.Do("HGETALL", cFlagKey+chanID))
+	if err != nil {
+		return nil, err
+	}
+
+	flagged := make(map[model.PlayerID]map[string]int)
+	for field, count := range values {
+		i := strings.LastIndex(field, ":")
+		if i < 0 {
+			continue
+		}
+		playerID, reason := model.PlayerID(field[:i]), field[i+1:]
+		if flagged[playerID] == nil {
+			flagged[playerID] = make(map[string]int)
+		}
+		flagged[playerID][reason] = count
+	}
+
+	return flagged, nil
+}
+
+// AddWebhookDelivery adds an entry to the webhook delivery log that keeps the latest keep entries
+func (r RedisDB) AddWebhookDelivery(data []byte, keep int) error {
+	defer dbAddWebhookDeliveryTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	if err := conn.Send("LPUSH", webhookLogKey, data); err != nil {
+		return errors.Wrap(err, "failed to execute command")
+	}
+	if err := conn.Send("LTRIM", webhookLogKey, 0, keep-1); err != nil {
+		return errors.Wrap(err, "failed to execute command")
+	}
+	return conn.Flush()
+}
+
+// WebhookDeliveries returns the latest entries of the webhook delivery log, newest first
+func (r RedisDB) WebhookDeliveries(limit int) ([][]byte, error) {
+	defer dbWebhookDeliveriesTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.ByteSlices(conn.Do("LRANGE", webhookLogKey, 0, limit-1))
+}
+
+// AddSchedule adds a schedule of a channel
+func (r RedisDB) AddSchedule(chanID, chanName, spec string) error {
+	defer dbAddScheduleTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	if err := conn.Send("HSET", scheduleKey+chanID, spec, chanName); err != nil {
+		return errors.Wrap(err, "failed to execute command")
+	}
+	if err := conn.Send("SADD", scheduleChanKey, chanID); err != nil {
+		return errors.Wrap(err, "failed to execute command")
+	}
+	return conn.Flush()
+}
+
+// RemoveSchedule removes a schedule of a channel
+func (r RedisDB) RemoveSchedule(chanID, spec string) error {
+	defer dbRemoveScheduleTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("HDEL", scheduleKey+chanID, spec); err != nil {
+		return err
+	}
+	n, err := redis.Int(conn.Do("HLEN", scheduleKey+chanID))
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = conn.Do("SREM", scheduleChanKey, chanID)
+
+	return err
+}
+
+// Schedules returns the schedule spec and channel name of every channel
+func (r RedisDB) Schedules() (map[string]map[string]string, error) {
+	defer dbSchedulesTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	chanIDs, err := redis.Strings(conn.Do("SMEMBERS", scheduleChanKey))
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make(map[string]map[string]string, len(chanIDs))
+	for _, chanID := range chanIDs {
+		specs, err := redis.StringMap(conn.Do("HGETALL", scheduleKey+chanID))
+		if err != nil {
+			return nil, err
+		}
+		if len(specs) > 0 {
+			schedules[chanID] = specs
+		}
+	}
+
+	return schedules, nil
+}
+
+// SaveTournament stores a tournament
+func (r RedisDB) SaveTournament(id string, data []byte) error {
+	defer dbSaveTournamentTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HSET", tournamentKey, id, data)
+
+	return err
+}
+
+// DeleteTournament removes a tournament and its results
+func (r RedisDB) DeleteTournament(id string) error {
+	defer dbDeleteTournamentTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	if err := conn.Send("HDEL", tournamentKey, id); err != nil {
+		return errors.Wrap(err, "failed to execute command")
+	}
+	if err := conn.Send("DEL", tournamentResultKey+id); err != nil {
+		return errors.Wrap(err, "failed to execute command")
+	}
+	return conn.Flush()
+}
+
+// Tournaments returns all tournaments by id
+func (r RedisDB) Tournaments() (map[string][]byte, error) {
+	defer dbTournamentsTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.ByteSlices(conn.Do("HGETALL", tournamentKey))
+	if err != nil {
+		return nil, err
+	}
+	tournaments := make(map[string][]byte, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		tournaments[string(values[i])] = values[i+1]
+	}
+
+	return tournaments, nil
+}
+
+// Tournament returns one tournament, nil if it does not exist
+func (r RedisDB) Tournament(id string) ([]byte, error) {
+	defer dbTournamentTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("HGET", tournamentKey, id))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+// SetTournamentResult sets a result of a tournament
+func (r RedisDB) SetTournamentResult(id, key string, value int64) error {
+	defer dbSetTournamentResultTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HSET", tournamentResultKey+id, key, value)
+
+	return err
+}
+
+// TournamentResults returns the results of a tournament
+func (r RedisDB) TournamentResults(id string) (map[string]int64, error) {
+	defer dbTournamentResultsTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64Map(conn.Do("HGETALL", tournamentResultKey+id))
 }
 
 func (r *RedisDB) IncRoundPlayed(chanID string) error {
@@ -242,45 +583,135 @@ func (r RedisDB) SaveScore(chanID, chanName string, scores model.Rank) error {
 			return errors.Wrap(err, "failed to execute command")
 		}
 	}
+
+	// time bucketed rankings expire once the retention after the end of the bucket has passed
+	now := time.Now()
+	for _, p := range Periods {
+		_, end := p.Bounds(now)
+		expireAt := int64(end.Add(PeriodRetention[p]).UnixNano() / int64(time.Millisecond))
+		for _, key := range []string{channelRankKey(chanID, p, now), playerRankKey(p, now)} {
+			for _, score := range scores {
+				if err := conn.Send("ZINCRBY", key, score.Score, score.PlayerID); err != nil {
+					return errors.Wrap(err, "failed to execute command")
+				}
+			}
+			if err := conn.Send("PEXPIREAT", key, expireAt); err != nil {
+				return errors.Wrap(err, "failed to execute command")
+			}
+		}
+	}
 	return conn.Flush()
 }
 
-func (r RedisDB) ChannelRanking(chanID string, limit int) (ranking model.Rank, err error) {
-	return r.getRanking(cRankKey+chanID, limit-1)
+// channelRankKey is the ranking key of the channel in the bucket of the period that contains t
+func channelRankKey(chanID string, p Period, t time.Time) string {
+	if p == PeriodAll {
+		return cRankKey + chanID
+	}
+	return cPeriodRankKey + p.Bucket(t) + "_" + chanID
+}
+
+// playerRankKey is the ranking key of all players in the bucket of the period that contains t
+func playerRankKey(p Period, t time.Time) string {
+	if p == PeriodAll {
+		return pRankKey
+	}
+	return pPeriodRankKey + p.Bucket(t)
 }
 
-func (r RedisDB) PlayerRanking(limit int) (model.Rank, error) {
-	return r.getRanking(pRankKey, limit-1)
+// ChannelRanking returns the ranking of the channel in the current bucket of the period
+func (r RedisDB) ChannelRanking(chanID string, period Period, limit int) (ranking model.Rank, err error) {
+	return r.ChannelRankingAt(chanID, period, time.Now(), limit)
 }
 
-func (r RedisDB) getRanking(key string, limit int) (ranking model.Rank, err error) {
-	defer dbGetRankingTimer.UpdateSince(time.Now())
+// ChannelRankingAt returns the ranking of the channel in the bucket of the period that contains t
+func (r RedisDB) ChannelRankingAt(chanID string, period Period, t time.Time, limit int) (ranking model.Rank, err error) {
+	return r.getRanking(channelRankKey(chanID, period, t), limit-1, r.ChannelRankMode(chanID))
+}
 
+// PlayerRanking returns the ranking of all players in the current bucket of the period
+func (r RedisDB) PlayerRanking(period Period, limit int) (model.Rank, error) {
+	return r.getRanking(playerRankKey(period, time.Now()), limit-1, model.StandardRank)
+}
+
+// ChannelRankingPage returns limit players of the channel ranking in the current bucket of the period, starting at offset
+func (r RedisDB) ChannelRankingPage(chanID string, period Period, offset, limit int) (model.Rank, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, nil
+	}
+	return r.getRankingRange(channelRankKey(chanID, period, time.Now()), offset, offset+limit-1, r.ChannelRankMode(chanID))
+}
+
+// ChannelRankingAround returns the player with n players above and n players below in the channel ranking
+// of the current bucket of the period. It returns empty ranking when the player has no score
+func (r RedisDB) ChannelRankingAround(chanID string, period Period, playerID model.PlayerID, n int) (model.Rank, error) {
+	defer dbChannelRankingAroundTimer.UpdateSince(time.Now())
+
+	key := channelRankKey(chanID, period, time.Now())
 	conn := r.pool.Get()
-	defer conn.Close()
+	idx, err := redis.Int(conn.Do("ZREVRANK", key, playerID))
+	conn.Close()
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	start := idx - n
+	if start < 0 {
+		start = 0
+	}
+	return r.getRankingRange(key, start, idx+n, r.ChannelRankMode(chanID))
+}
+
+// ChannelRankMode returns how the positions of tied players are numbered in the rankings
+// of the channel, set with the channel config "rankMode"
+func (r RedisDB) ChannelRankMode(chanID string) model.RankMode {
+	mode, err := r.ChannelConfig(chanID, "rankMode", "")
+	if err != nil {
+		return model.StandardRank
+	}
+	return model.ParseRankMode(mode)
+}
+
+func (r RedisDB) getRanking(key string, limit int, mode model.RankMode) (ranking model.Rank, err error) {
 	if limit <= 0 {
 		limit = -1
 	}
+	return r.getRankingRange(key, 0, limit, mode)
+}
+
+// getRankingRange returns the ranking from index start to stop, inclusive
+func (r RedisDB) getRankingRange(key string, start, stop int, mode model.RankMode) (ranking model.Rank, err error) {
+	defer dbGetRankingTimer.UpdateSince(time.Now())
 
-	values, err := redis.Values(conn.Do("ZREVRANGE", key, 0, limit, "WITHSCORES"))
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Values(conn.Do("ZREVRANGE", key, start, stop, "WITHSCORES"))
 	if err != nil {
 		return nil, err
 	}
 
 	ids := make([]interface{}, 0, len(values))
 	ids = append(ids, pNameKey)
-	pos := 0
 	for len(values) > 0 {
 		var ps model.PlayerScore
 		values, err = redis.Scan(values, &ps.PlayerID, &ps.Score)
 		if err != nil {
 			return nil, err
 		}
-		pos++
-		ps.Position = pos
 		ids = append(ids, ps.PlayerID)
 		ranking = append(ranking, ps)
 	}
+	// ZREVRANGE already has the order of model.Rank
+	ranking.SetPosition(mode)
+	if start > 0 && len(ranking) > 0 {
+		if err := offsetPositions(conn, key, start, ranking, mode); err != nil {
+			return nil, err
+		}
+	}
 
 	// get all name
 	if len(ranking) > 0 {
@@ -296,6 +727,39 @@ func (r RedisDB) getRanking(key string, limit int) (ranking model.Rank, err erro
 	return ranking, nil
 }
 
+// offsetPositions continues the positions of a ranking window that starts at index start
+// after the players before the window, the first players may tie with them
+func offsetPositions(conn redis.Conn, key string, start int, ranking model.Rank, mode model.RankMode) error {
+	above := fmt.Sprintf("(%d", ranking[0].Score)
+	if mode == model.DenseRank {
+		scores, err := redis.Ints(conn.Do("ZREVRANGEBYSCORE", key, "+inf", above, "WITHSCORES"))
+		if err != nil {
+			return err
+		}
+		distinct := make(map[int]bool)
+		for i := 1; i < len(scores); i += 2 {
+			distinct[scores[i]] = true
+		}
+		for i := range ranking {
+			ranking[i].Position += len(distinct)
+		}
+		return nil
+	}
+
+	higher, err := redis.Int(conn.Do("ZCOUNT", key, above, "+inf"))
+	if err != nil {
+		return err
+	}
+	for i := range ranking {
+		if ranking[i].Score == ranking[0].Score {
+			ranking[i].Position = higher + 1
+		} else {
+			ranking[i].Position += start
+		}
+	}
+	return nil
+}
+
 func (r RedisDB) PlayerScore(playerID model.PlayerID) (ps model.PlayerScore, err error) {
 	return r.getScore(pRankKey, playerID)
 }
@@ -317,9 +781,12 @@ func (r RedisDB) getScore(key string, playerID model.PlayerID) (ps model.PlayerS
 	if ps.Score, err = redis.Int(conn.Do("ZSCORE", key, playerID)); err != nil {
 		return ps, err
 	}
-	if ps.Position, err = redis.Int(conn.Do("ZREVRANK", key, playerID)); err != nil {
+	// players with the same score share the position
+	higher, err := redis.Int(conn.Do("ZCOUNT", key, fmt.Sprintf("(%d", ps.Score), "+inf"))
+	if err != nil {
 		return ps, err
 	}
+	ps.Position = higher + 1
 
 	return ps, nil
 }
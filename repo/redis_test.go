@@ -2,6 +2,7 @@ package repo
 
 import (
 	"testing"
+	"time"
 
 	"github.com/yulrizka/fam100/model"
 )
@@ -29,7 +30,7 @@ func TestSaveScore(t *testing.T) {
 	}
 
 	// test ranking in specific channel
-	chanRank, err := r.ChannelRanking(chanID, 100)
+	chanRank, err := r.ChannelRanking(chanID, PeriodAll, 100)
 	if err != nil {
 		t.Error(err)
 	}
@@ -51,7 +52,7 @@ func TestSaveScore(t *testing.T) {
 	if err := r.SaveScore(chanID2, chanName2, ranking); err != nil {
 		t.Error(err)
 	}
-	playerRank, err := r.PlayerRanking(100)
+	playerRank, err := r.PlayerRanking(PeriodAll, 100)
 	if err != nil {
 		t.Error(err)
 	}
@@ -82,6 +83,9 @@ func TestSaveScore(t *testing.T) {
 	if want, got := 45, ps.Score; want != got {
 		t.Errorf("playerID, want %d got %d", want, got)
 	}
+	if want, got := 1, ps.Position; want != got {
+		t.Errorf("position, want %d got %d", want, got)
+	}
 
 	// test playerChannelScore
 	ps, err = r.PlayerChannelScore(chanID, pid)
@@ -98,3 +102,358 @@ func TestSaveScore(t *testing.T) {
 		t.Errorf("playerID, want %d got %d", want, got)
 	}
 }
+
+func TestPracticeScore(t *testing.T) {
+	r := new(RedisDB)
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	scores := []struct {
+		score int
+		best  bool
+	}{
+		{10, true},
+		{8, false},
+		{15, true},
+	}
+	for _, s := range scores {
+		best, err := r.SavePracticeScore(model.PlayerScore{PlayerID: "ID1", Name: "Name 1", Score: s.score})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, got := s.best, best; want != got {
+			t.Errorf("score %d best, want %t got %t", s.score, want, got)
+		}
+	}
+
+	rank, err := r.PracticeRanking(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(rank); want != got {
+		t.Fatalf("rank length, want %d got %d", want, got)
+	}
+	if want, got := 15, rank[0].Score; want != got {
+		t.Errorf("best score, want %d got %d", want, got)
+	}
+	if pr, err := r.PlayerRanking(PeriodAll, 10); err != nil || len(pr) != 0 {
+		t.Errorf("practice score should not be in player ranking, got %v %v", pr, err)
+	}
+}
+
+func TestPlayStreak(t *testing.T) {
+	r := new(RedisDB)
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	days := []struct {
+		day, yesterday string
+		streak         int64
+	}{
+		{"2017-01-01", "2016-12-31", 1},
+		{"2017-01-01", "2016-12-31", 1},
+		{"2017-01-02", "2017-01-01", 2},
+		{"2017-01-04", "2017-01-03", 1},
+	}
+	for _, d := range days {
+		streak, err := r.PlayStreak("ID1", d.day, d.yesterday)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, got := d.streak, streak; want != got {
+			t.Errorf("streak on %s, want %d got %d", d.day, want, got)
+		}
+	}
+}
+
+func TestAddBadge(t *testing.T) {
+	r := new(RedisDB)
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Unix(1483228800, 0)
+	for i, want := range []bool{true, false} {
+		added, err := r.AddBadge("ID1", "sweep", at.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := added; want != got {
+			t.Errorf("add badge #%d, want %t got %t", i, want, got)
+		}
+	}
+
+	badges, err := r.Badges("ID1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := at, badges["sweep"]; !want.Equal(got) {
+		t.Errorf("badge time, want %s got %s", want, got)
+	}
+}
+
+func TestPlayerStats(t *testing.T) {
+	r := new(RedisDB)
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	games := []struct {
+		chanID string
+		game   model.PlayerStats
+	}{
+		{"c1", model.PlayerStats{Games: 1, Rounds: 3, Correct: 4, TopAnswers: 1, AnswerTime: 20 * time.Second, BestGame: 50}},
+		{"c1", model.PlayerStats{Games: 1, Rounds: 1, Correct: 1, AnswerTime: 4 * time.Second, BestGame: 10}},
+		{"c2", model.PlayerStats{Games: 1, Rounds: 2, Correct: 2, AnswerTime: 6 * time.Second, BestGame: 70}},
+	}
+	for _, g := range games {
+		if err := r.SavePlayerStats(g.chanID, "ID1", g.game); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := r.PlayerStats("c1", "ID1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := games[0].game.Add(games[1].game); want != stats {
+		t.Errorf("channel stats, want %+v got %+v", want, stats)
+	}
+	stats, err = r.PlayerStats("", "ID1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := games[0].game.Add(games[1].game).Add(games[2].game); want != stats {
+		t.Errorf("global stats, want %+v got %+v", want, stats)
+	}
+}
+
+func TestRatings(t *testing.T) {
+	r := new(RedisDB)
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	ratings := map[model.PlayerID]model.Rating{
+		"ID1": {Value: 1516.4, Games: 10},
+		"ID2": {Value: 1532, Games: 1},
+	}
+	ids := []model.PlayerID{"ID1", "ID2"}
+	save := func(map[model.PlayerID]model.Rating) map[model.PlayerID]model.Rating { return ratings }
+	if err := r.UpdateRatings(ids, 10, save); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.Ratings([]model.PlayerID{"ID1", "ID2", "ID3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 2, len(got); want != got {
+		t.Fatalf("ratings, want %d got %d", want, got)
+	}
+	if want, got := ratings["ID1"], got["ID1"]; want != got {
+		t.Errorf("rating, want %+v got %+v", want, got)
+	}
+
+	rank, err := r.RatingRanking(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(rank); want != got {
+		t.Fatalf("provisional rating should not be ranked, want %d got %d", want, got)
+	}
+	if want, got := 1516, rank[0].Score; want != got {
+		t.Errorf("ranked rating, want %d got %d", want, got)
+	}
+}
+
+func TestPeriodRanking(t *testing.T) {
+	r := new(RedisDB)
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	scores := model.Rank{
+		{PlayerID: "ID1", Name: "Name 1", Score: 10},
+		{PlayerID: "ID2", Name: "Name 2", Score: 20},
+	}
+	if err := r.SaveScore("c1", "chan 1", scores); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range Periods {
+		rank, err := r.ChannelRanking("c1", p, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, got := 2, len(rank); want != got {
+			t.Fatalf("%s channel ranking, want %d got %d", p, want, got)
+		}
+		if want, got := model.PlayerID("ID2"), rank[0].PlayerID; want != got {
+			t.Errorf("%s channel ranking, want %s got %s", p, want, got)
+		}
+		if rank, err := r.PlayerRanking(p, 10); err != nil || len(rank) != 2 {
+			t.Errorf("%s player ranking, got %v %v", p, rank, err)
+		}
+	}
+
+	// tied players share the position and keep the in memory order
+	tie := model.Rank{{PlayerID: "ID3", Name: "Name 3", Score: 20}}
+	if err := r.SaveScore("c1", "chan 1", tie); err != nil {
+		t.Fatal(err)
+	}
+	rank, err := r.ChannelRanking("c1", PeriodAll, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := scores.Add(tie)
+	for i := range want {
+		if want[i].PlayerID != rank[i].PlayerID || want[i].Position != rank[i].Position {
+			t.Errorf("rank[%d], want %+v got %+v", i, want[i], rank[i])
+		}
+	}
+
+	rank, err = r.ChannelRankingAt("c1", PeriodMonth, time.Now().AddDate(0, -1, 0), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 0, len(rank); want != got {
+		t.Errorf("last month ranking, want %d got %d", want, got)
+	}
+}
+
+func TestRankingWindow(t *testing.T) {
+	r := new(RedisDB)
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	scores := model.Rank{
+		{PlayerID: "ID1", Name: "Name 1", Score: 50},
+		{PlayerID: "ID2", Name: "Name 2", Score: 40},
+		{PlayerID: "ID3", Name: "Name 3", Score: 40},
+		{PlayerID: "ID4", Name: "Name 4", Score: 30},
+		{PlayerID: "ID5", Name: "Name 5", Score: 20},
+	}
+	if err := r.SaveScore("c1", "chan 1", scores); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := r.ChannelRankingPage("c1", PeriodAll, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 2, len(page); want != got {
+		t.Fatalf("page length, want %d got %d", want, got)
+	}
+	// ID2 is tied with ID3 on the previous page
+	if want, got := model.PlayerID("ID2"), page[0].PlayerID; want != got {
+		t.Errorf("page[0], want %s got %s", want, got)
+	}
+	if want, got := 2, page[0].Position; want != got {
+		t.Errorf("page[0] position, want %d got %d", want, got)
+	}
+	if want, got := 4, page[1].Position; want != got {
+		t.Errorf("page[1] position, want %d got %d", want, got)
+	}
+
+	around, err := r.ChannelRankingAround("c1", PeriodAll, "ID5", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []model.PlayerID{"ID2", "ID4", "ID5"} {
+		if got := around[i].PlayerID; want != got {
+			t.Errorf("around[%d], want %s got %s", i, want, got)
+		}
+	}
+
+	around, err = r.ChannelRankingAround("c1", PeriodAll, "ID6", 2)
+	if err != nil || len(around) != 0 {
+		t.Errorf("player without score, got %v %v", around, err)
+	}
+	// dense positions of the window are the same as in the full ranking
+	if err := r.SetChannelConfig("c1", "rankMode", "dense"); err != nil {
+		t.Fatal(err)
+	}
+	page, err = r.ChannelRankingPage("c1", PeriodAll, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{2, 3} {
+		if got := page[i].Position; want != got {
+			t.Errorf("dense page[%d] position, want %d got %d", i, want, got)
+		}
+	}
+}
+
+func TestStartSeason(t *testing.T) {
+	r := new(RedisDB)
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	scores := model.Rank{
+		{PlayerID: "ID1", Name: "Name 1", Score: 10},
+		{PlayerID: "ID2", Name: "Name 2", Score: 20},
+	}
+	if err := r.SaveScore("c1", "chan 1", scores); err != nil {
+		t.Fatal(err)
+	}
+
+	archived, err := r.StartSeason()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, archived; want != got {
+		t.Errorf("archived season, want %d got %d", want, got)
+	}
+	if season, err := r.Season(); err != nil || season != 2 {
+		t.Errorf("running season, want 2 got %d %v", season, err)
+	}
+
+	for _, chanID := range []string{"c1", ""} {
+		rank, err := r.SeasonRanking(chanID, 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, got := 2, len(rank); want != got {
+			t.Fatalf("season ranking %q, want %d got %d", chanID, want, got)
+		}
+		if want, got := model.PlayerID("ID2"), rank[0].PlayerID; want != got {
+			t.Errorf("season ranking %q, want %s got %s", chanID, want, got)
+		}
+	}
+
+	if rank, err := r.ChannelRanking("c1", PeriodAll, 10); err != nil || len(rank) != 0 {
+		t.Errorf("live channel ranking should be empty, got %v %v", rank, err)
+	}
+	if rank, err := r.PlayerRanking(PeriodAll, 10); err != nil || len(rank) != 0 {
+		t.Errorf("live player ranking should be empty, got %v %v", rank, err)
+	}
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/pkg/errors"
+	"github.com/yulrizka/fam100/model"
+)
+
+// A season is the period of the all-time rankings. Starting a season renames the
+// live channel and player rankings to the archive of the season and the next
+// score starts the rankings of the new season. Seasons are numbered from 1.
+
+var (
+	nextSeasonScript = redis.NewScript(1, `
+local season = tonumber(redis.call('GET', KEYS[1]) or '1')
+redis.call('SET', KEYS[1], season + 1)
+return season
+`)
+	archiveRankScript = redis.NewScript(2, `
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('RENAME', KEYS[1], KEYS[2])
+end
+return 1
+`)
+)
+
+// Season returns the number of the running season
+func (r RedisDB) Season() (int, error) {
+	defer dbSeasonTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	season, err := redis.Int(conn.Do("GET", seasonKey))
+	if err == redis.ErrNil {
+		return 1, nil
+	}
+	return season, err
+}
+
+// StartSeason archives the rankings of the running season and starts the next one,
+// it returns the number of the archived season. The season number is bumped first and
+// the rankings are archived one by one, a score saved in between counts for the
+// archived season
+func (r RedisDB) StartSeason() (int, error) {
+	defer dbStartSeasonTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	season, err := redis.Int(nextSeasonScript.Do(conn, seasonKey))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to start season")
+	}
+	channels, err := redis.Strings(conn.Do("HKEYS", cNameKey))
+	if err != nil {
+		return season, errors.Wrap(err, "failed to get channels")
+	}
+	archive := strconv.Itoa(season)
+	for _, chanID := range channels {
+		if _, err := archiveRankScript.Do(conn, cRankKey+chanID, cSeasonRankKey+archive+"_"+chanID); err != nil {
+			return season, errors.Wrapf(err, "failed to archive ranking of %s", chanID)
+		}
+	}
+	if _, err := archiveRankScript.Do(conn, pRankKey, pSeasonRankKey+archive); err != nil {
+		return season, errors.Wrap(err, "failed to archive player ranking")
+	}
+
+	return season, nil
+}
+
+// SeasonRanking returns the archived ranking of a season, chanID is empty for all channels
+func (r RedisDB) SeasonRanking(chanID string, season, limit int) (model.Rank, error) {
+	key := pSeasonRankKey + strconv.Itoa(season)
+	if chanID != "" {
+		key = cSeasonRankKey + strconv.Itoa(season) + "_" + chanID
+	}
+	mode := model.StandardRank
+	if chanID != "" {
+		mode = r.ChannelRankMode(chanID)
+	}
+	return r.getRanking(key, limit-1, mode)
+}
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/yulrizka/fam100/model"
+)
+
+// The record of a player is kept per channel and for all channels. Answer time
+// is stored in millisecond.
+
+var savePlayerStatsScript = redis.NewScript(2, `
+for i = 1, 2 do
+	redis.call('HINCRBY', KEYS[i], 'games', ARGV[1])
+	redis.call('HINCRBY', KEYS[i], 'rounds', ARGV[2])
+	redis.call('HINCRBY', KEYS[i], 'correct', ARGV[3])
+	redis.call('HINCRBY', KEYS[i], 'top_answers', ARGV[4])
+	redis.call('HINCRBY', KEYS[i], 'answer_time', ARGV[5])
+	local best = tonumber(redis.call('HGET', KEYS[i], 'best_game') or '0')
+	if tonumber(ARGV[6]) > best then
+		redis.call('HSET', KEYS[i], 'best_game', ARGV[6])
+	end
+end
+return 1
+`)
+
+func playerRecordKey(chanID string, playerID model.PlayerID) string {
+	if chanID == "" {
+		return pRecordKey + string(playerID)
+	}
+	return pRecordKey + chanID + "_" + string(playerID)
+}
+
+// SavePlayerStats adds the record of a game to the record of the player in the channel and in all channels
+func (r RedisDB) SavePlayerStats(chanID string, playerID model.PlayerID, game model.PlayerStats) error {
+	defer dbSavePlayerStatsTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := savePlayerStatsScript.Do(conn,
+		playerRecordKey(chanID, playerID), playerRecordKey("", playerID),
+		game.Games, game.Rounds, game.Correct, game.TopAnswers, int64(game.AnswerTime/time.Millisecond), game.BestGame,
+	)
+	return err
+}
+
+// PlayerStats returns the record of the player in the channel, or in all channels if chanID is empty
+func (r RedisDB) PlayerStats(chanID string, playerID model.PlayerID) (model.PlayerStats, error) {
+	defer dbPlayerStatsTimer.UpdateSince(time.Now())
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Int64Map(conn.Do("HGETALL", playerRecordKey(chanID, playerID)))
+	if err != nil {
+		return model.PlayerStats{}, err
+	}
+
+	return model.PlayerStats{
+		Games:      values["games"],
+		Rounds:     values["rounds"],
+		Correct:    values["correct"],
+		TopAnswers: values["top_answers"],
+		AnswerTime: time.Duration(values["answer_time"]) * time.Millisecond,
+		BestGame:   int(values["best_game"]),
+	}, nil
+}
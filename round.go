@@ -12,12 +12,47 @@ import (
 // round represents with one question
 type round struct {
 	id        int64
+	number    int
 	q         qna.Question
 	state     State
 	correct   []model.PlayerID // correct answer answered by a player, "" means not answered
 	players   map[model.PlayerID]model.Player
 	highlight map[int]bool
 
+	// answeredAt is the time each correct answer was received
+	answeredAt []time.Time
+	startedAt  time.Time
+	scoring    ScoreStrategy
+
+	// hintLevel is the current hint level, hints is the hint level when each answer was answered
+	hintLevel int
+	hints     []int
+
+	// suddenDeath round ends on the first correct answer and gives no points
+	suddenDeath bool
+
+	// active are players who sent a message in this round
+	active    map[model.PlayerID]bool
+	skipVotes map[model.PlayerID]bool
+
+	// flagged are player and reason that was flagged in this round
+	flagged map[string]bool
+
+	// team mode, playing is the team that is allowed to answer. stealing is the
+	// team that gets one guess after the playing team fails to reveal the board
+	playing      string
+	stealing     string
+	stolenBy     string
+	stealGuessed bool
+
+	// strikes is the number of wrong answers in the round
+	strikes       int
+	strikeLimit   int
+	playerStrikes map[model.PlayerID]int
+
+	// guesses is the number of answers sent by players in the round
+	guesses int
+
 	endAt time.Time
 }
 
@@ -30,6 +65,15 @@ func newRound(question qna.Question, players map[model.PlayerID]model.Player) (*
 		players:   players,
 		highlight: make(map[int]bool),
 		endAt:     time.Now().Add(RoundDuration).Round(time.Second),
+
+		playerStrikes: make(map[model.PlayerID]int),
+		answeredAt:    make([]time.Time, len(question.Answers)),
+		hints:         make([]int, len(question.Answers)),
+		active:        make(map[model.PlayerID]bool),
+		skipVotes:     make(map[model.PlayerID]bool),
+		flagged:       make(map[string]bool),
+		startedAt:     time.Now(),
+		scoring:       RawScore{},
 	}, nil
 }
 
@@ -38,7 +82,7 @@ func (r *round) timeLeft() time.Duration {
 }
 
 // questionText construct QNAMessage which contains questions, answers and score
-func (r *round) questionText(gameID string, showUnAnswered bool) QNAMessage {
+func (r *round) questionText(chanID string, gameID int64, showUnAnswered bool) QNAMessage {
 	ras := make([]roundAnswers, len(r.q.Answers))
 
 	for i, ans := range r.q.Answers {
@@ -53,21 +97,35 @@ func (r *round) questionText(gameID string, showUnAnswered bool) QNAMessage {
 		if r.highlight[i] {
 			ra.Highlight = true
 		}
+		if !ra.Answered && r.hintLevel > 0 {
+			ra.HintLevel = r.hintLevel
+			ra.Hint = hintText(ans.Text[0], r.hintLevel)
+		}
 		ras[i] = ra
 	}
 
 	msg := QNAMessage{
-		ChanID:         gameID,
+		GameID:         gameID,
+		ChanID:         chanID,
+		Round:          r.number,
 		QuestionText:   r.q.Text,
 		QuestionID:     r.q.ID,
 		ShowUnanswered: showUnAnswered,
 		TimeLeft:       r.timeLeft(),
 		Answers:        ras,
+		Strikes:        r.strikes,
+		StrikeLimit:    r.strikeLimit,
 	}
 
 	return msg
 }
 
+// strike records a wrong answer from a player
+func (r *round) strike(p model.Player) {
+	r.strikes++
+	r.playerStrikes[p.ID]++
+}
+
 func (r *round) finished() bool {
 	answered := 0
 	for _, pID := range r.correct {
@@ -79,42 +137,115 @@ func (r *round) finished() bool {
 	return answered == len(r.q.Answers)
 }
 
+// answeredBy returns the number of answers of the board answered by the player
+func (r *round) answeredBy(playerID model.PlayerID) int {
+	answered := 0
+	for _, pID := range r.correct {
+		if pID == playerID {
+			answered++
+		}
+	}
+
+	return answered
+}
+
+// topAnswer returns whether no answer of the board has a higher score than answer idx
+func (r *round) topAnswer(idx int) bool {
+	for _, ans := range r.q.Answers {
+		if ans.Score > r.q.Answers[idx].Score {
+			return false
+		}
+	}
+
+	return true
+}
+
+// recap summarizes the round, the MVP is the player with the most points and on
+// a tie the one who reached the points first
+func (r *round) recap() RoundRecap {
+	recap := RoundRecap{Guesses: r.guesses}
+	for i, pID := range r.correct {
+		if pID == "" {
+			recap.Unanswered = append(recap.Unanswered, r.q.Answers[i].String())
+		}
+	}
+
+	answers := r.scoredAnswers()
+	if len(answers) == 0 {
+		return recap
+	}
+	first := answers[0]
+	recap.Fastest = FastestAnswer{Player: r.players[first.PlayerID], Answer: first.Text, Elapsed: first.Elapsed}
+
+	// details are ordered by answer time
+	points := make(map[model.PlayerID]int)
+	for _, d := range r.scoreDetails() {
+		points[d.PlayerID] += d.Points
+		if points[d.PlayerID] > recap.MVP.Score || recap.MVP.PlayerID == "" {
+			recap.MVP = model.PlayerScore{PlayerID: d.PlayerID, Name: d.Name, Score: points[d.PlayerID]}
+		}
+	}
+
+	return recap
+}
+
 // ranking generates a rank for current round which contains player, answers and score
 func (r *round) ranking() model.Rank {
 	var roundScores model.Rank
 	lookup := make(map[model.PlayerID]model.PlayerScore)
-	for i, pID := range r.correct {
-		if pID != "" {
-			score := r.q.Answers[i].Score
-			if ps, ok := lookup[pID]; !ok {
-				lookup[pID] = model.PlayerScore{
-					PlayerID: pID,
-					Name:     r.players[pID].Name,
-					Score:    score,
-				}
-			} else {
-				ps = lookup[pID]
-				ps.Score += score
-				lookup[pID] = ps
+	for _, d := range r.scoreDetails() {
+		if ps, ok := lookup[d.PlayerID]; !ok {
+			lookup[d.PlayerID] = model.PlayerScore{
+				PlayerID: d.PlayerID,
+				Name:     d.Name,
+				Score:    d.Points,
 			}
+		} else {
+			ps.Score += d.Points
+			lookup[d.PlayerID] = ps
 		}
 	}
 
 	for _, ps := range lookup {
 		roundScores = append(roundScores, ps)
 	}
-	sort.Sort(roundScores)
-	for i := range roundScores {
-		roundScores[i].Position = i + 1
-	}
+	roundScores.Sort(model.StandardRank)
 
 	return roundScores
 }
 
-func (r *round) answer(p model.Player, text string) (correct, answered bool, index int) {
-	if r.state != RoundStarted {
+// teamRanking groups the round ranking by team. When the round was stolen,
+// all of the round points goes to the stealing team
+func (r *round) teamRanking(teams map[model.PlayerID]string) model.TeamRank {
+	lookup := make(map[string]int)
+	for _, ps := range r.ranking() {
+		team, ok := teams[ps.PlayerID]
+		if !ok {
+			continue
+		}
+		if r.stolenBy != "" {
+			team = r.stolenBy
+		}
+		lookup[team] += ps.Score
+	}
+
+	var teamScores model.TeamRank
+	for team, score := range lookup {
+		teamScores = append(teamScores, model.TeamScore{Team: team, Score: score})
+	}
+	sort.Sort(teamScores)
+	for i := range teamScores {
+		teamScores[i].Position = i + 1
+	}
+
+	return teamScores
+}
+
+func (r *round) answer(p model.Player, text string, at time.Time) (correct, answered bool, index int) {
+	if r.state != RoundStarted && r.state != RoundSteal {
 		return false, false, -1
 	}
+	r.guesses++
 
 	if _, ok := r.players[p.ID]; !ok {
 		r.players[p.ID] = p
@@ -125,6 +256,8 @@ func (r *round) answer(p model.Player, text string) (correct, answered bool, ind
 			return correct, true, i
 		}
 		r.correct[i] = p.ID
+		r.answeredAt[i] = at
+		r.hints[i] = r.hintLevel
 		r.highlight[i] = true
 
 		return correct, false, i
@@ -0,0 +1,269 @@
+package fam100
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/qna"
+)
+
+func testQuestion(t *testing.T, id string) qna.Question {
+	t.Helper()
+	questionDB, err := qna.NewText("qna/questions.txt")
+	if err != nil {
+		t.Fatalf("failed to load questions: %v", err)
+	}
+	q, err := questionDB.GetQuestion(id)
+	if err != nil {
+		t.Fatalf("failed to get question: %v", err)
+	}
+
+	return q
+}
+
+func TestRoundTeamRanking(t *testing.T) {
+	// apa yang orang katakan sambil memegang pipi orang lain*33:gemes*20:lucu*12:sayang*...
+	q := testQuestion(t, "1")
+	foo := model.Player{ID: "1", Name: "foo"}
+	bar := model.Player{ID: "2", Name: "bar"}
+	baz := model.Player{ID: "3", Name: "baz"}
+	teams := map[model.PlayerID]string{"1": "merah", "2": "merah", "3": "biru"}
+
+	r, err := newRound(q, make(map[model.PlayerID]model.Player))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.state = RoundStarted
+	r.answer(foo, "gemes", time.Now())
+	r.answer(bar, "lucu", time.Now())
+	r.answer(baz, "sayang", time.Now())
+
+	rank := r.teamRanking(teams)
+	if want, got := 2, len(rank); want != got {
+		t.Fatalf("len(rank) want %d got %d", want, got)
+	}
+	if want, got := "merah", rank[0].Team; want != got {
+		t.Errorf("rank[0].Team want %s got %s", want, got)
+	}
+	if want, got := 53, rank[0].Score; want != got {
+		t.Errorf("rank[0].Score want %d got %d", want, got)
+	}
+
+	// stolen round gives all points to the stealing team
+	r.stolenBy = "biru"
+	rank = r.teamRanking(teams)
+	if want, got := 1, len(rank); want != got {
+		t.Fatalf("len(rank) want %d got %d", want, got)
+	}
+	if want, got := 65, rank[0].Score; want != got {
+		t.Errorf("rank[0].Score want %d got %d", want, got)
+	}
+}
+
+func TestRoundStrike(t *testing.T) {
+	q := testQuestion(t, "1")
+	foo := model.Player{ID: "1", Name: "foo"}
+	bar := model.Player{ID: "2", Name: "bar"}
+
+	r, err := newRound(q, make(map[model.PlayerID]model.Player))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.state = RoundStarted
+	r.strike(foo)
+	r.strike(foo)
+	r.strike(bar)
+
+	if want, got := 3, r.strikes; want != got {
+		t.Errorf("strikes want %d got %d", want, got)
+	}
+	if want, got := 2, r.playerStrikes[foo.ID]; want != got {
+		t.Errorf("foo strikes want %d got %d", want, got)
+	}
+	if want, got := 3, r.questionText("", 0, false).Strikes; want != got {
+		t.Errorf("QNAMessage.Strikes want %d got %d", want, got)
+	}
+}
+
+func TestVoteSkip(t *testing.T) {
+	q := testQuestion(t, "1")
+	g := &Game{skipPercent: 50}
+	sub := g.Subscribe("test", 10)
+	r, err := newRound(q, make(map[model.PlayerID]model.Player))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.state = RoundStarted
+	for _, id := range []model.PlayerID{"1", "2", "3", "4"} {
+		r.active[id] = true
+	}
+
+	if g.voteSkip(SkipVoteMessage{Player: model.Player{ID: "1"}}, r) {
+		t.Errorf("1 of 4 votes should not skip the round")
+	}
+	if g.voteSkip(SkipVoteMessage{Player: model.Player{ID: "1"}}, r) {
+		t.Errorf("same player should only vote once")
+	}
+	if !g.voteSkip(SkipVoteMessage{Player: model.Player{ID: "2"}}, r) {
+		t.Errorf("2 of 4 votes should skip the round")
+	}
+
+	msg := (<-sub.C).(SkipVoteMessage)
+	if want, got := 2, msg.Needed; want != got {
+		t.Errorf("needed want %d got %d", want, got)
+	}
+}
+
+func TestFinalRank(t *testing.T) {
+	now := time.Now()
+	g := &Game{
+		rank: model.Rank{
+			{PlayerID: "2", Score: 20},
+			{PlayerID: "3", Score: 20},
+			{PlayerID: "4", Score: 20},
+			{PlayerID: "1", Score: 10},
+		},
+		firstAnswerAt: map[model.PlayerID]time.Time{"2": now.Add(time.Second), "3": now, "4": now},
+		answerCount:   map[model.PlayerID]int{"2": 1, "3": 1, "4": 3},
+	}
+
+	rank := g.finalRank()
+	for i, want := range []model.PlayerID{"4", "3", "2", "1"} {
+		if got := rank[i].PlayerID; want != got {
+			t.Errorf("rank[%d] want %s got %s", i, want, got)
+		}
+		if want, got := i+1, rank[i].Position; want != got {
+			t.Errorf("rank[%d].Position want %d got %d", i, want, got)
+		}
+	}
+
+	g.suddenDeathWinner = "2"
+	if want, got := model.PlayerID("2"), g.finalRank()[0].PlayerID; want != got {
+		t.Errorf("sudden death winner want %s got %s", want, got)
+	}
+	if want, got := 3, len(g.tiedLeaders()); want != got {
+		t.Errorf("tied leaders want %d got %d", want, got)
+	}
+}
+
+func TestRoundTopAnswer(t *testing.T) {
+	// apa yang orang katakan sambil memegang pipi orang lain*33:gemes*20:lucu*12:sayang*...
+	q := testQuestion(t, "1")
+	r, err := newRound(q, make(map[model.PlayerID]model.Player))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := true, r.topAnswer(0); want != got {
+		t.Errorf("top answer gemes want %t got %t", want, got)
+	}
+	if want, got := false, r.topAnswer(1); want != got {
+		t.Errorf("top answer lucu want %t got %t", want, got)
+	}
+}
+
+func TestRoundRecap(t *testing.T) {
+	// apa yang orang katakan sambil memegang pipi orang lain*33:gemes*20:lucu*12:sayang*...
+	q := testQuestion(t, "1")
+	foo := model.Player{ID: "1", Name: "foo"}
+	bar := model.Player{ID: "2", Name: "bar"}
+
+	r, err := newRound(q, make(map[model.PlayerID]model.Player))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recap := r.recap(); recap.MVP.PlayerID != "" || len(recap.Unanswered) != len(q.Answers) {
+		t.Errorf("recap without answer, got %+v", recap)
+	}
+
+	r.state = RoundStarted
+	r.answer(bar, "xyz", r.startedAt.Add(time.Second))
+	r.answer(bar, "lucu", r.startedAt.Add(2*time.Second))
+	r.answer(foo, "gemes", r.startedAt.Add(3*time.Second))
+	r.answer(bar, "sayang", r.startedAt.Add(4*time.Second))
+
+	recap := r.recap()
+	if want, got := foo.ID, recap.MVP.PlayerID; want != got {
+		t.Errorf("MVP, want %s got %s", want, got)
+	}
+	if want, got := 33, recap.MVP.Score; want != got {
+		t.Errorf("MVP score, want %d got %d", want, got)
+	}
+	if want, got := (FastestAnswer{Player: bar, Answer: "lucu", Elapsed: 2 * time.Second}), recap.Fastest; want != got {
+		t.Errorf("fastest, want %+v got %+v", want, got)
+	}
+	if want, got := len(q.Answers)-3, len(recap.Unanswered); want != got {
+		t.Errorf("unanswered, want %d got %d", want, got)
+	}
+	if want, got := 4, recap.Guesses; want != got {
+		t.Errorf("guesses, want %d got %d", want, got)
+	}
+
+	// sudden death does not repeat the recap of the last round
+	g := &Game{roundRecap: recap}
+	r.suddenDeath = true
+	if err := g.updateRanking(r); err != nil {
+		t.Fatal(err)
+	}
+	if g.roundRecap.MVP.PlayerID != "" || g.roundRecap.Guesses != 0 {
+		t.Errorf("recap after sudden death should be empty, got %+v", g.roundRecap)
+	}
+}
+
+func TestSteal(t *testing.T) {
+	// apa yang orang katakan sambil memegang pipi orang lain*33:gemes*20:lucu*12:sayang*...
+	q := testQuestion(t, "1")
+	foo := model.Player{ID: "1", Name: "foo"}
+	bar := model.Player{ID: "2", Name: "bar"}
+
+	for _, tt := range []struct {
+		guess   string
+		stolen  string
+		success bool
+	}{
+		{"gemes", "merah", true},
+		{"salah", "", false},
+	} {
+		g := &Game{
+			players:       map[model.PlayerID]model.Player{foo.ID: foo, bar.ID: bar},
+			teams:         map[model.PlayerID]string{foo.ID: "merah", bar.ID: "biru"},
+			teamNames:     []string{"biru", "merah"},
+			steal:         true,
+			limiter:       newAnswerLimiter(0, 0),
+			firstAnswerAt: make(map[model.PlayerID]time.Time),
+			lastAnswerAt:  make(map[model.PlayerID]time.Time),
+			answerCount:   make(map[model.PlayerID]int),
+		}
+		sub := g.Subscribe("test", 10)
+		r, err := newRound(q, g.players)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.state = RoundStarted
+		r.playing = "biru"
+
+		if !g.startSteal(r, 1) {
+			t.Fatalf("steal should start")
+		}
+		msg := (<-sub.C).(StateMessage)
+		if want, got := RoundSteal, msg.State; want != got {
+			t.Errorf("state want %s got %s", want, got)
+		}
+		if want, got := "merah", msg.Team; want != got {
+			t.Errorf("stealing team want %s got %s", want, got)
+		}
+
+		// only the stealing team can guess
+		if g.canAnswer(bar, r) {
+			t.Errorf("playing team should not answer during steal")
+		}
+		g.handleMessage(TextMessage{Player: foo, Text: tt.guess, ReceivedAt: r.startedAt.Add(10 * time.Second)}, r)
+		if !r.stealGuessed {
+			t.Errorf("guess %q should use the steal guess", tt.guess)
+		}
+		if want, got := tt.stolen, r.stolenBy; want != got {
+			t.Errorf("guess %q stolen by want %q got %q", tt.guess, want, got)
+		}
+	}
+}
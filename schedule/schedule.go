@@ -0,0 +1,233 @@
+// Package schedule parses recurring times of a game. A schedule is either a
+// cron expression "minute hour day-of-month month day-of-week" or a weekly time
+// such as "20:00", "daily 20:00" or "mon-fri 19:30". Times are in the location
+// of the schedule.
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Schedule is a parsed recurring time
+type Schedule struct {
+	Spec     string
+	Location *time.Location
+
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: monthNames}
+	dowField    = field{name: "day of week", min: 0, max: 7, names: dayNames}
+)
+
+// Parse parses a cron or weekly schedule, nil loc is UTC
+func Parse(spec string, loc *time.Location) (*Schedule, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	fields := strings.Fields(spec)
+
+	s := &Schedule{Spec: spec, Location: loc}
+	var err error
+	switch len(fields) {
+	case 5:
+		err = s.parseCron(fields)
+	case 1, 2:
+		err = s.parseWeekly(fields)
+	default:
+		err = errors.Errorf("invalid schedule %q, expecting 5 cron fields or [days] HH:MM", spec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Schedule) parseCron(fields []string) (err error) {
+	if s.minute, err = minuteField.parse(fields[0]); err != nil {
+		return err
+	}
+	if s.hour, err = hourField.parse(fields[1]); err != nil {
+		return err
+	}
+	if s.dom, err = domField.parse(fields[2]); err != nil {
+		return err
+	}
+	if s.month, err = monthField.parse(fields[3]); err != nil {
+		return err
+	}
+	if s.dow, err = dowField.parse(fields[4]); err != nil {
+		return err
+	}
+	s.domAny, s.dowAny = fields[2] == "*", fields[4] == "*"
+	s.normalizeSunday()
+
+	return nil
+}
+
+func (s *Schedule) parseWeekly(fields []string) (err error) {
+	at := fields[len(fields)-1]
+	parts := strings.Split(at, ":")
+	if len(parts) != 2 {
+		return errors.Errorf("invalid time %q, expecting HH:MM", at)
+	}
+	if s.hour, err = hourField.parse(parts[0]); err != nil {
+		return err
+	}
+	if s.minute, err = minuteField.parse(parts[1]); err != nil {
+		return err
+	}
+	if s.hour&(s.hour-1) != 0 || s.minute&(s.minute-1) != 0 {
+		return errors.Errorf("invalid time %q, expecting HH:MM", at)
+	}
+
+	s.dom, s.domAny = domField.all(), true
+	s.month = monthField.all()
+	s.dow, s.dowAny = dowField.all(), true
+	if len(fields) == 2 && fields[0] != "daily" {
+		if s.dow, err = dowField.parse(fields[0]); err != nil {
+			return err
+		}
+		s.dowAny = false
+	}
+	s.normalizeSunday()
+
+	return nil
+}
+
+// normalizeSunday treats day of week 7 as sunday
+func (s *Schedule) normalizeSunday() {
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+}
+
+func (f field) all() uint64 {
+	var bits uint64
+	for i := f.min; i <= f.max; i++ {
+		bits |= 1 << uint(i)
+	}
+	return bits
+}
+
+// parse parses comma separated values, ranges and steps such as "1,5-10,*/15"
+func (f field) parse(expr string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, errors.Errorf("invalid %s step %q", f.name, part)
+			}
+			part = part[:i]
+		}
+
+		lo, hi := f.min, f.max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = f.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				hi = f.max
+			}
+			if hi < lo {
+				return 0, errors.Errorf("invalid %s range %q", f.name, part)
+			}
+		}
+		for i := lo; i <= hi; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[s]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, errors.Errorf("invalid %s %q", f.name, s)
+	}
+	return v, nil
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	// like cron, a restricted day of month or day of week is enough
+	if !s.domAny && !s.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Match returns whether t is a scheduled minute
+func (s *Schedule) Match(t time.Time) bool {
+	t = t.In(s.Location)
+	return has(s.minute, t.Minute()) && has(s.hour, t.Hour()) && has(s.month, int(t.Month())) && s.matchDay(t)
+}
+
+// Next returns the first scheduled minute after t, zero time if there is none within 5 years
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.Location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.Location)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.Location)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.Location)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,89 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*3600)
+	// 2017-03-01 is a wednesday
+	from := time.Date(2017, 3, 1, 10, 30, 0, 0, jakarta)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"20:00", time.Date(2017, 3, 1, 20, 0, 0, 0, jakarta)},
+		{"daily 09:15", time.Date(2017, 3, 2, 9, 15, 0, 0, jakarta)},
+		{"10:30", time.Date(2017, 3, 2, 10, 30, 0, 0, jakarta)},
+		{"sat,sun 19:30", time.Date(2017, 3, 4, 19, 30, 0, 0, jakarta)},
+		{"mon-fri 08:00", time.Date(2017, 3, 2, 8, 0, 0, 0, jakarta)},
+		{"*/15 * * * *", time.Date(2017, 3, 1, 10, 45, 0, 0, jakarta)},
+		{"0 20 * * 5", time.Date(2017, 3, 3, 20, 0, 0, 0, jakarta)},
+		{"0 20 * * 7", time.Date(2017, 3, 5, 20, 0, 0, 0, jakarta)},
+		{"0 12 15 * *", time.Date(2017, 3, 15, 12, 0, 0, 0, jakarta)},
+		{"0 12 1 jan *", time.Date(2018, 1, 1, 12, 0, 0, 0, jakarta)},
+		{"0 0 31 * *", time.Date(2017, 3, 31, 0, 0, 0, 0, jakarta)},
+		// day of month or day of week when both are restricted
+		{"0 0 20 * mon", time.Date(2017, 3, 6, 0, 0, 0, 0, jakarta)},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.spec, jakarta)
+		if err != nil {
+			t.Errorf("%q: %s", tt.spec, err)
+			continue
+		}
+		if want, got := tt.want, s.Next(from); !want.Equal(got) {
+			t.Errorf("%q: want %s got %s", tt.spec, want, got)
+		}
+		if !s.Match(tt.want) {
+			t.Errorf("%q: should match %s", tt.spec, tt.want)
+		}
+	}
+}
+
+func TestNextLocation(t *testing.T) {
+	s, err := Parse("20:00", time.FixedZone("WIB", 7*3600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+	if want, got := time.Date(2017, 3, 1, 13, 0, 0, 0, time.UTC), s.Next(from); !want.Equal(got) {
+		t.Errorf("want %s got %s", want, got)
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 feb *", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(time.Now()); !got.IsZero() {
+		t.Errorf("want zero time got %s", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"25:00",
+		"20:61",
+		"2000",
+		"*:00",
+		"someday 20:00",
+		"0 20 * *",
+		"60 * * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"0 20 * * * *",
+	}
+	for _, spec := range specs {
+		if _, err := Parse(spec, nil); err == nil {
+			t.Errorf("%q should be invalid", spec)
+		}
+	}
+}
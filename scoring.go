@@ -0,0 +1,177 @@
+package fam100
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/yulrizka/fam100/model"
+)
+
+// DefaultScoreStrategy is used when channel has no "scoring" configuration
+var DefaultScoreStrategy = "raw"
+
+// ScoreStrategies available for the channel to pick
+var ScoreStrategies = map[string]ScoreStrategy{
+	"raw":    RawScore{},
+	"speed":  SpeedBonus{Max: 20, Window: 60 * time.Second},
+	"first":  FirstAnswerBonus{Bonus: 10},
+	"streak": StreakMultiplier{Step: 0.5, Max: 3},
+	"flat":   FlatPoints{Value: 10},
+}
+
+// ScoredAnswer is a correct answer that is going to be scored
+type ScoredAnswer struct {
+	PlayerID model.PlayerID
+	Index    int    // index of the answer in the question
+	Text     string // answer text
+	Score    int    // survey score of the answer
+	Elapsed  time.Duration
+	Order    int // 0 for the first correct answer of the round
+	Streak   int // consecutive correct answers by the same player including this one
+	Hint     int // hint level shown before it was answered
+}
+
+// ScoreDetail explains the points a player got for an answer
+type ScoreDetail struct {
+	PlayerID model.PlayerID `json:"playerID"`
+	Name     string         `json:"name"`
+	Answer   string         `json:"answer"`
+	Base     int            `json:"base"`
+	Points   int            `json:"points"`
+	Reason   string         `json:"reason"`
+}
+
+// ScoreStrategy calculates points for a correct answer
+type ScoreStrategy interface {
+	Points(a ScoredAnswer) (points int, reason string)
+}
+
+// RawScore gives the survey score of the answer
+type RawScore struct{}
+
+func (RawScore) Points(a ScoredAnswer) (int, string) {
+	return a.Score, ""
+}
+
+// SpeedBonus gives up to Max extra points that decays linearly over Window since the round started
+type SpeedBonus struct {
+	Max    int
+	Window time.Duration
+}
+
+func (s SpeedBonus) Points(a ScoredAnswer) (int, string) {
+	if a.Elapsed >= s.Window || s.Window <= 0 {
+		return a.Score, ""
+	}
+	bonus := int(float64(s.Max) * float64(s.Window-a.Elapsed) / float64(s.Window))
+	if bonus <= 0 {
+		return a.Score, ""
+	}
+
+	return a.Score + bonus, T("bonus cepat")
+}
+
+// FirstAnswerBonus gives extra points to the first correct answer of the round
+type FirstAnswerBonus struct {
+	Bonus int
+}
+
+func (f FirstAnswerBonus) Points(a ScoredAnswer) (int, string) {
+	if a.Order != 0 {
+		return a.Score, ""
+	}
+
+	return a.Score + f.Bonus, T("bonus pertama")
+}
+
+// StreakMultiplier multiplies the score by 1 + Step for each consecutive answer by the same player, up to Max
+type StreakMultiplier struct {
+	Step float64
+	Max  float64
+}
+
+func (s StreakMultiplier) Points(a ScoredAnswer) (int, string) {
+	if a.Streak <= 1 {
+		return a.Score, ""
+	}
+	multiplier := math.Min(1+s.Step*float64(a.Streak-1), s.Max)
+
+	return int(math.Round(float64(a.Score) * multiplier)), T("beruntun") + " x" + strconv.FormatFloat(multiplier, 'f', -1, 64)
+}
+
+// FlatPoints gives the same points for every correct answer
+type FlatPoints struct {
+	Value int
+}
+
+func (f FlatPoints) Points(a ScoredAnswer) (int, string) {
+	return f.Value, ""
+}
+
+// scoreStrategy returns the strategy by name or the raw score if it doesn't exists
+func scoreStrategy(name string) ScoreStrategy {
+	if s, ok := ScoreStrategies[name]; ok {
+		return s
+	}
+
+	return RawScore{}
+}
+
+// scoredAnswers returns correct answers of the round in the order they were answered
+func (r *round) scoredAnswers() []ScoredAnswer {
+	var answers []ScoredAnswer
+	for i, pID := range r.correct {
+		if pID == "" {
+			continue
+		}
+		answers = append(answers, ScoredAnswer{
+			PlayerID: pID,
+			Index:    i,
+			Text:     r.q.Answers[i].String(),
+			Score:    r.q.Answers[i].Score,
+			Elapsed:  r.answeredAt[i].Sub(r.startedAt),
+			Hint:     r.hints[i],
+		})
+	}
+	sort.SliceStable(answers, func(i, j int) bool {
+		return answers[i].Elapsed < answers[j].Elapsed
+	})
+
+	for i := range answers {
+		answers[i].Order = i
+		answers[i].Streak = 1
+		if i > 0 && answers[i-1].PlayerID == answers[i].PlayerID {
+			answers[i].Streak = answers[i-1].Streak + 1
+		}
+	}
+
+	return answers
+}
+
+// scoreDetails scores every correct answer of the round with the round score strategy
+func (r *round) scoreDetails() []ScoreDetail {
+	answers := r.scoredAnswers()
+	details := make([]ScoreDetail, 0, len(answers))
+	for _, a := range answers {
+		points, reason := r.scoring.Points(a)
+		if a.Hint > 0 {
+			points = hintPoints(points, a.Hint)
+			if reason != "" {
+				reason += ", "
+			}
+			reason += T("petunjuk")
+		}
+		details = append(details, ScoreDetail{
+			PlayerID: a.PlayerID,
+			Name:     r.players[a.PlayerID].Name,
+			Answer:   a.Text,
+			Base:     a.Score,
+			Points:   points,
+			Reason:   reason,
+		})
+	}
+
+	return details
+}
@@ -0,0 +1,66 @@
+package fam100
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yulrizka/fam100/model"
+)
+
+func TestScoreStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy ScoreStrategy
+		answer   ScoredAnswer
+		want     int
+	}{
+		{"raw", RawScore{}, ScoredAnswer{Score: 30}, 30},
+		{"speed at start", SpeedBonus{Max: 20, Window: 60 * time.Second}, ScoredAnswer{Score: 30}, 50},
+		{"speed half", SpeedBonus{Max: 20, Window: 60 * time.Second}, ScoredAnswer{Score: 30, Elapsed: 30 * time.Second}, 40},
+		{"speed expired", SpeedBonus{Max: 20, Window: 60 * time.Second}, ScoredAnswer{Score: 30, Elapsed: 90 * time.Second}, 30},
+		{"first", FirstAnswerBonus{Bonus: 10}, ScoredAnswer{Score: 30}, 40},
+		{"not first", FirstAnswerBonus{Bonus: 10}, ScoredAnswer{Score: 30, Order: 1}, 30},
+		{"streak", StreakMultiplier{Step: 0.5, Max: 3}, ScoredAnswer{Score: 10, Streak: 2}, 15},
+		{"streak max", StreakMultiplier{Step: 0.5, Max: 3}, ScoredAnswer{Score: 10, Streak: 10}, 30},
+		{"flat", FlatPoints{Value: 10}, ScoredAnswer{Score: 30}, 10},
+	}
+
+	for _, tt := range tests {
+		if got, _ := tt.strategy.Points(tt.answer); got != tt.want {
+			t.Errorf("%s: want %d got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRoundScoreDetails(t *testing.T) {
+	// apa yang orang katakan sambil memegang pipi orang lain*33:gemes*20:lucu*12:sayang*...
+	q := testQuestion(t, "1")
+	foo := model.Player{ID: "1", Name: "foo"}
+	bar := model.Player{ID: "2", Name: "bar"}
+
+	r, err := newRound(q, make(map[model.PlayerID]model.Player))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.state = RoundStarted
+	r.scoring = StreakMultiplier{Step: 1, Max: 3}
+	r.answer(bar, "sayang", r.startedAt.Add(3*time.Second))
+	r.answer(foo, "gemes", r.startedAt.Add(1*time.Second))
+	r.answer(foo, "lucu", r.startedAt.Add(2*time.Second))
+
+	details := r.scoreDetails()
+	if want, got := 3, len(details); want != got {
+		t.Fatalf("len(details) want %d got %d", want, got)
+	}
+	if want, got := 40, details[1].Points; want != got {
+		t.Errorf("streak points want %d got %d", want, got)
+	}
+
+	rank := r.ranking()
+	if want, got := model.PlayerID("1"), rank[0].PlayerID; want != got {
+		t.Errorf("rank[0] want %s got %s", want, got)
+	}
+	if want, got := 73, rank[0].Score; want != got {
+		t.Errorf("rank[0].Score want %d got %d", want, got)
+	}
+}
@@ -0,0 +1,157 @@
+// Package stats records the statistics of players from the events of their games.
+//
+// The record of a game is collected while the game is running and is saved when
+// the final ranking is published, games that never finish are not recorded. The
+// record of a running game is kept in the store at the end of every round so it
+// survives when the game is resumed after a restart or by another instance.
+package stats
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+)
+
+// CheckpointKind is the store checkpoint kind of the records of running games
+const CheckpointKind = "stats"
+
+// Store keeps the record of players, it is implemented by repo.DefaultDB
+type Store interface {
+	SavePlayerStats(chanID string, playerID model.PlayerID, game model.PlayerStats) error
+	SaveCheckpoint(kind, id string, data []byte) error
+	Checkpoint(kind, id string) ([]byte, error)
+	DeleteCheckpoint(kind, id string) error
+}
+
+// Recorder collects the record of players of running games. It is not safe for concurrent use
+type Recorder struct {
+	Store Store
+
+	// Expire drops the record of a game without events for this long, it is longer
+	// than a game can be paused by a restart
+	Expire time.Duration
+
+	games map[int64]*game
+}
+
+type game struct {
+	Players   map[model.PlayerID]*model.PlayerStats `json:"players"`
+	LastRound map[model.PlayerID]int                `json:"lastRound"` // last round with a correct answer of the player
+	updatedAt time.Time
+}
+
+// NewRecorder creates a recorder that saves to the store
+func NewRecorder(store Store) *Recorder {
+	return &Recorder{Store: store, Expire: time.Hour, games: make(map[int64]*game)}
+}
+
+// Handle records the event, the record of the players is saved with the final ranking
+func (r *Recorder) Handle(ev fam100.Event) error {
+	switch msg := ev.(type) {
+	case fam100.AnswerMessage:
+		g := r.game(msg.GameID)
+		s, ok := g.Players[msg.Player.ID]
+		if !ok {
+			s = new(model.PlayerStats)
+			g.Players[msg.Player.ID] = s
+		}
+		s.Correct++
+		if msg.Top {
+			s.TopAnswers++
+		}
+		s.AnswerTime += msg.Elapsed
+		if g.LastRound[msg.Player.ID] != msg.Round {
+			s.Rounds++
+			g.LastRound[msg.Player.ID] = msg.Round
+		}
+
+	case fam100.RankMessage:
+		if !msg.Final {
+			return r.save(msg.GameID)
+		}
+		g := r.game(msg.GameID)
+		r.finish(msg.GameID)
+
+		var err error
+		for _, ps := range msg.Rank {
+			var s model.PlayerStats
+			if gs, ok := g.Players[ps.PlayerID]; ok {
+				s = *gs
+			}
+			s.Games = 1
+			s.BestGame = ps.Score
+			if saveErr := r.Store.SavePlayerStats(msg.ChanID, ps.PlayerID, s); saveErr != nil {
+				err = errors.Wrapf(saveErr, "failed to save stats of %s", ps.PlayerID)
+			}
+		}
+		return err
+
+	case fam100.StateMessage:
+		if msg.State == fam100.Finished {
+			// the game finished without a final ranking
+			r.finish(msg.GameID)
+		}
+		r.expire(time.Now())
+	}
+
+	return nil
+}
+
+// game returns the record of a running game, loading it from the store if the game
+// was started before a restart or by another instance
+func (r *Recorder) game(gameID int64) *game {
+	g, ok := r.games[gameID]
+	if !ok {
+		g = &game{}
+		if data, err := r.Store.Checkpoint(CheckpointKind, strconv.FormatInt(gameID, 10)); err == nil && data != nil {
+			// a broken record starts over
+			json.Unmarshal(data, g)
+		}
+		if g.Players == nil {
+			g.Players = make(map[model.PlayerID]*model.PlayerStats)
+		}
+		if g.LastRound == nil {
+			g.LastRound = make(map[model.PlayerID]int)
+		}
+		r.games[gameID] = g
+	}
+	g.updatedAt = time.Now()
+
+	return g
+}
+
+// save keeps the record of the running game in the store
+func (r *Recorder) save(gameID int64) error {
+	g, ok := r.games[gameID]
+	if !ok {
+		return nil
+	}
+	g.updatedAt = time.Now()
+	data, err := json.Marshal(g)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode record of game %d", gameID)
+	}
+
+	return r.Store.SaveCheckpoint(CheckpointKind, strconv.FormatInt(gameID, 10), data)
+}
+
+// finish drops the record of a game that ended
+func (r *Recorder) finish(gameID int64) {
+	delete(r.games, gameID)
+	// best effort, an old record is never loaded again
+	r.Store.DeleteCheckpoint(CheckpointKind, strconv.FormatInt(gameID, 10))
+}
+
+// expire drops the record of games that stopped sending events, such as a game whose
+// lease was lost or that was canceled by a restart
+func (r *Recorder) expire(now time.Time) {
+	for gameID, g := range r.games {
+		if now.Sub(g.updatedAt) > r.Expire {
+			r.finish(gameID)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yulrizka/fam100"
+	"github.com/yulrizka/fam100/model"
+	"github.com/yulrizka/fam100/repo"
+)
+
+func TestRecorder(t *testing.T) {
+	db := new(repo.MemoryDB)
+	r := NewRecorder(db)
+	foo := model.Player{ID: "1", Name: "foo"}
+	bar := model.Player{ID: "2", Name: "bar"}
+
+	events := []fam100.Event{
+		fam100.AnswerMessage{GameID: 1, ChanID: "c1", Round: 1, Player: foo, Top: true, Elapsed: 2 * time.Second},
+		fam100.AnswerMessage{GameID: 1, ChanID: "c1", Round: 1, Player: foo, Elapsed: 6 * time.Second},
+		fam100.AnswerMessage{GameID: 2, ChanID: "c2", Round: 1, Player: foo, Elapsed: time.Second},
+		fam100.AnswerMessage{GameID: 1, ChanID: "c1", Round: 2, Player: foo, Elapsed: 4 * time.Second},
+		fam100.AnswerMessage{GameID: 1, ChanID: "c1", Round: 2, Player: bar, Elapsed: 3 * time.Second},
+		fam100.RankMessage{GameID: 1, ChanID: "c1", Round: 2, Rank: model.Rank{{PlayerID: foo.ID, Score: 60}, {PlayerID: bar.ID, Score: 10}}},
+		fam100.RankMessage{GameID: 1, ChanID: "c1", Round: 3, Final: true, Rank: model.Rank{{PlayerID: foo.ID, Score: 60}, {PlayerID: bar.ID, Score: 10}}},
+	}
+	for _, e := range events {
+		if err := r.Handle(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := db.PlayerStats("c1", foo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := model.PlayerStats{Games: 1, Rounds: 2, Correct: 3, TopAnswers: 1, AnswerTime: 12 * time.Second, BestGame: 60}
+	if want != stats {
+		t.Errorf("foo stats, want %+v got %+v", want, stats)
+	}
+	if stats, _ := db.PlayerStats("c2", foo.ID); stats.Games != 0 {
+		t.Errorf("unfinished game should not be saved, got %+v", stats)
+	}
+	if want, got := 1, len(r.games); want != got {
+		t.Errorf("running games, want %d got %d", want, got)
+	}
+
+	r.Handle(fam100.StateMessage{GameID: 2, ChanID: "c2", State: fam100.Finished})
+	if want, got := 0, len(r.games); want != got {
+		t.Errorf("running games after finished, want %d got %d", want, got)
+	}
+}
+
+func TestRecorderResume(t *testing.T) {
+	db := new(repo.MemoryDB)
+	foo := model.Player{ID: "1", Name: "foo"}
+
+	// the game is resumed by another recorder after the first round
+	r := NewRecorder(db)
+	r.Handle(fam100.AnswerMessage{GameID: 1, ChanID: "c1", Round: 1, Player: foo, Elapsed: time.Second})
+	r.Handle(fam100.RankMessage{GameID: 1, ChanID: "c1", Round: 1, Rank: model.Rank{{PlayerID: foo.ID, Score: 30}}})
+
+	resumed := NewRecorder(db)
+	resumed.Handle(fam100.AnswerMessage{GameID: 1, ChanID: "c1", Round: 2, Player: foo, Elapsed: time.Second})
+	final := fam100.RankMessage{GameID: 1, ChanID: "c1", Round: 3, Final: true, Rank: model.Rank{{PlayerID: foo.ID, Score: 50}}}
+	if err := resumed.Handle(final); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := db.PlayerStats("c1", foo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := int64(2), stats.Correct; want != got {
+		t.Errorf("correct, want %d got %d", want, got)
+	}
+	if data, _ := db.Checkpoint(CheckpointKind, "1"); data != nil {
+		t.Errorf("record of a finished game should be deleted")
+	}
+
+	// the first recorder never sees the end of the game
+	r.Expire = time.Millisecond
+	time.Sleep(2 * time.Millisecond)
+	r.Handle(fam100.StateMessage{GameID: 2, ChanID: "c2", State: fam100.Started})
+	if want, got := 0, len(r.games); want != got {
+		t.Errorf("running games after expire, want %d got %d", want, got)
+	}
+}
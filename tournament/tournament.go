@@ -0,0 +1,319 @@
+// Package tournament runs a single elimination bracket between channels. Each
+// match is played in both channels at the same time with the same questions and
+// the channel with the highest aggregate score advances to the next round.
+//
+// The tournament only keeps the registered channels and the schedule, the
+// bracket is computed from the results so that results reported by different
+// bot instances never overwrite each other.
+package tournament
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Entrant is a channel registered to the tournament
+type Entrant struct {
+	ChanID string `json:"chanID"`
+	Name   string `json:"name"`
+}
+
+// Tournament is a bracket between channels
+type Tournament struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Seed     int64         `json:"seed"`
+	Entrants []Entrant     `json:"entrants"` // ordered by seed
+	StartAt  time.Time     `json:"startAt"`  // zero while registering
+	Interval time.Duration `json:"interval"` // time between rounds
+}
+
+// New creates a tournament that accepts registration
+func New(id, name string, seed int64) *Tournament {
+	return &Tournament{ID: id, Name: name, Seed: seed}
+}
+
+// Started returns whether the bracket is fixed
+func (t *Tournament) Started() bool {
+	return !t.StartAt.IsZero()
+}
+
+// Register adds a channel to the tournament
+func (t *Tournament) Register(chanID, name string) error {
+	if t.Started() {
+		return errors.New("tournament already started")
+	}
+	for _, e := range t.Entrants {
+		if e.ChanID == chanID {
+			return errors.Errorf("channel %s is already registered", chanID)
+		}
+	}
+	t.Entrants = append(t.Entrants, Entrant{ChanID: chanID, Name: name})
+
+	return nil
+}
+
+// Unregister removes a channel from the tournament
+func (t *Tournament) Unregister(chanID string) error {
+	if t.Started() {
+		return errors.New("tournament already started")
+	}
+	for i, e := range t.Entrants {
+		if e.ChanID == chanID {
+			t.Entrants = append(t.Entrants[:i], t.Entrants[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.Errorf("channel %s is not registered", chanID)
+}
+
+// Start fixes the bracket, round n is played at at + (n-1) * interval. A channel
+// that did not finish its match within forfeit loses it, the interval can not be
+// shorter so that every match is decided before the next round starts
+func (t *Tournament) Start(at time.Time, interval, forfeit time.Duration) error {
+	if t.Started() {
+		return errors.New("tournament already started")
+	}
+	if len(t.Entrants) < 2 {
+		return errors.New("tournament needs at least 2 channels")
+	}
+	if at.IsZero() || interval <= 0 {
+		return errors.New("invalid start time or interval")
+	}
+	if interval < forfeit {
+		return errors.Errorf("interval %s is shorter than the forfeit time %s", interval, forfeit)
+	}
+	t.StartAt, t.Interval = at, interval
+
+	return nil
+}
+
+// QuestionSeed returns the seed of the question sequence of a round, both
+// channels of a match play the same questions
+func (t *Tournament) QuestionSeed(round int) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s:%d:%d", t.ID, t.Seed, round)
+	return int64(h.Sum64() >> 1)
+}
+
+// Results keys. A channel reports when its match is started and its aggregate score
+// when the game is finished
+
+// StartedKey is the result key of a started match, the value is the game id
+func StartedKey(matchID, chanID string) string {
+	return fmt.Sprintf("%s:%s:started", matchID, chanID)
+}
+
+// ScoreKey is the result key of the aggregate score of a channel in a match
+func ScoreKey(matchID, chanID string) string {
+	return fmt.Sprintf("%s:%s:score", matchID, chanID)
+}
+
+// Side is a channel in a match
+type Side struct {
+	Entrant
+	Seed     int   `json:"seed"`
+	Score    int   `json:"score"`
+	Reported bool  `json:"reported"`
+	GameID   int64 `json:"gameID"` // 0 if not started
+}
+
+// Match between two channels, B is empty for a bye
+type Match struct {
+	ID      string    `json:"id"`
+	Round   int       `json:"round"`
+	StartAt time.Time `json:"startAt"`
+	A       *Side     `json:"a"`
+	B       *Side     `json:"b"`
+	Winner  string    `json:"winner"` // chanID of the winner, empty if undecided
+}
+
+// Ready returns whether both channels of the match are known
+func (m *Match) Ready() bool {
+	return m.A != nil && m.B != nil
+}
+
+// Bye returns whether the only channel of the match advances without playing
+func (m *Match) Bye() bool {
+	return m.Round == 1 && m.A != nil && m.B == nil
+}
+
+// Side returns the side of a channel, nil if the channel does not play in the match
+func (m *Match) Side(chanID string) *Side {
+	if m.A != nil && m.A.ChanID == chanID {
+		return m.A
+	}
+	if m.B != nil && m.B.ChanID == chanID {
+		return m.B
+	}
+	return nil
+}
+
+// Bracket is the state of a tournament computed from its results
+type Bracket struct {
+	Rounds   [][]*Match `json:"rounds"`
+	Champion *Entrant   `json:"champion"`
+}
+
+// Bracket computes the matches from the results. Higher seeds play against lower
+// seeds in the first round, a tie is won by the higher seed
+func (t *Tournament) Bracket(results map[string]int64) *Bracket {
+	b := new(Bracket)
+	if !t.Started() {
+		return b
+	}
+
+	size := 1
+	for size < len(t.Entrants) {
+		size *= 2
+	}
+	side := func(seed int) *Side {
+		if seed >= len(t.Entrants) {
+			return nil
+		}
+		return &Side{Entrant: t.Entrants[seed], Seed: seed + 1}
+	}
+
+	var matches []*Match
+	seeds := order(size)
+	for i := 0; i < size/2; i++ {
+		matches = append(matches, &Match{A: side(seeds[2*i]), B: side(seeds[2*i+1])})
+	}
+
+	for round := 1; len(matches) > 0; round++ {
+		for i, m := range matches {
+			m.ID = fmt.Sprintf("r%dm%d", round, i+1)
+			m.Round = round
+			m.StartAt = t.StartAt.Add(time.Duration(round-1) * t.Interval)
+			decide(m, results)
+		}
+		b.Rounds = append(b.Rounds, matches)
+		if len(matches) == 1 {
+			if w := matches[0].winner(); w != nil {
+				b.Champion = &w.Entrant
+			}
+			break
+		}
+
+		next := make([]*Match, len(matches)/2)
+		for i := range next {
+			next[i] = &Match{A: advance(matches[2*i].winner()), B: advance(matches[2*i+1].winner())}
+			if next[i].A == nil && next[i].B != nil {
+				next[i].A, next[i].B = next[i].B, nil
+			}
+		}
+		matches = next
+	}
+
+	return b
+}
+
+// order returns the seeds of a bracket of size so that the best seeds meet last
+func order(size int) []int {
+	seeds := []int{0}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, s := range seeds {
+			next = append(next, s, n-1-s)
+		}
+		seeds = next
+	}
+	return seeds
+}
+
+func decide(m *Match, results map[string]int64) {
+	if m.Bye() {
+		m.Winner = m.A.ChanID
+		return
+	}
+	for _, s := range []*Side{m.A, m.B} {
+		if s == nil {
+			continue
+		}
+		s.GameID = results[StartedKey(m.ID, s.ChanID)]
+		score, ok := results[ScoreKey(m.ID, s.ChanID)]
+		s.Score, s.Reported = int(score), ok
+	}
+	if !m.Ready() || !m.A.Reported || !m.B.Reported {
+		return
+	}
+	m.Winner = m.A.ChanID
+	if m.B.Score > m.A.Score || (m.B.Score == m.A.Score && m.B.Seed < m.A.Seed) {
+		m.Winner = m.B.ChanID
+	}
+}
+
+func (m *Match) winner() *Side {
+	if m.Winner == "" {
+		return nil
+	}
+	return m.Side(m.Winner)
+}
+
+// advance copies the winner to the next round
+func advance(s *Side) *Side {
+	if s == nil {
+		return nil
+	}
+	return &Side{Entrant: s.Entrant, Seed: s.Seed}
+}
+
+// Due returns matches that should be played at now, the match is ready, started
+// and not decided yet
+func (b *Bracket) Due(now time.Time) []*Match {
+	var due []*Match
+	for _, round := range b.Rounds {
+		for _, m := range round {
+			if m.Ready() && m.Winner == "" && !now.Before(m.StartAt) {
+				due = append(due, m)
+			}
+		}
+	}
+	return due
+}
+
+// Finished returns whether the tournament has a champion
+func (b *Bracket) Finished() bool {
+	return b.Champion != nil
+}
+
+// String formats the bracket as text
+func (b *Bracket) String() string {
+	var lines []string
+	name := func(s *Side) string {
+		if s == nil {
+			return "?"
+		}
+		return s.Name
+	}
+	for i, round := range b.Rounds {
+		lines = append(lines, fmt.Sprintf("Round %d", i+1))
+		for _, m := range round {
+			switch {
+			case m.Bye():
+				lines = append(lines, fmt.Sprintf("  %s (bye)", name(m.A)))
+			case m.Ready() && (m.A.Reported || m.B.Reported):
+				lines = append(lines, fmt.Sprintf("  %s %s vs %s %s", name(m.A), score(m.A), score(m.B), name(m.B)))
+			default:
+				lines = append(lines, fmt.Sprintf("  %s vs %s", name(m.A), name(m.B)))
+			}
+		}
+	}
+	if b.Champion != nil {
+		lines = append(lines, fmt.Sprintf("Champion: %s", b.Champion.Name))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func score(s *Side) string {
+	if !s.Reported {
+		return "-"
+	}
+	return fmt.Sprint(s.Score)
+}
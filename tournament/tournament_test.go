@@ -0,0 +1,124 @@
+package tournament
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTournament(t *testing.T, n int) *Tournament {
+	tr := New("t1", "piala", 1)
+	for i := 1; i <= n; i++ {
+		if err := tr.Register(fmt.Sprintf("c%d", i), fmt.Sprintf("chan %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tr
+}
+
+func TestRegister(t *testing.T) {
+	tr := newTournament(t, 1)
+	if err := tr.Register("c1", "again"); err == nil {
+		t.Errorf("duplicate channel should fail")
+	}
+	if err := tr.Start(time.Now(), time.Hour, time.Hour); err == nil {
+		t.Errorf("start with 1 channel should fail")
+	}
+	if err := tr.Register("c2", "chan 2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Start(time.Now(), time.Hour, 2*time.Hour); err == nil {
+		t.Errorf("interval shorter than forfeit should fail")
+	}
+	if err := tr.Start(time.Now(), time.Hour, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Register("c3", "chan 3"); err == nil {
+		t.Errorf("register after start should fail")
+	}
+}
+
+func TestBracket(t *testing.T) {
+	start := time.Date(2017, 3, 1, 20, 0, 0, 0, time.UTC)
+	tr := newTournament(t, 5)
+	if err := tr.Start(start, time.Hour, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(map[string]int64)
+	b := tr.Bracket(results)
+	if want, got := 3, len(b.Rounds); want != got {
+		t.Fatalf("rounds, want %d got %d", want, got)
+	}
+	// seed 1 vs 8, 4 vs 5, 2 vs 7, 3 vs 6
+	want := "[c1 vs ? c4 vs c5 c2 vs ? c3 vs ?]"
+	if got := pairs(b.Rounds[0]); want != got {
+		t.Errorf("first round, want %s got %s", want, got)
+	}
+	if got := len(b.Due(start)); got != 1 {
+		t.Errorf("due matches, want 1 got %d", got)
+	}
+
+	// c4 vs c5 is a tie, c4 is the higher seed
+	results[ScoreKey("r1m2", "c4")] = 10
+	results[ScoreKey("r1m2", "c5")] = 10
+	b = tr.Bracket(results)
+	if want, got := "[c1 vs c4 c2 vs c3]", pairs(b.Rounds[1]); want != got {
+		t.Errorf("second round, want %s got %s", want, got)
+	}
+	if got := b.Rounds[1][0].StartAt; !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("second round start at %s", got)
+	}
+	if got := len(b.Due(start)); got != 0 {
+		t.Errorf("second round is not due yet, got %d", got)
+	}
+
+	results[ScoreKey("r2m1", "c1")] = 20
+	results[ScoreKey("r2m1", "c4")] = 35
+	results[ScoreKey("r2m2", "c2")] = 0
+	results[ScoreKey("r2m2", "c3")] = 5
+	results[StartedKey("r3m1", "c4")] = 42
+	b = tr.Bracket(results)
+	final := b.Rounds[2][0]
+	if want, got := "[c4 vs c3]", pairs(b.Rounds[2]); want != got {
+		t.Errorf("final, want %s got %s", want, got)
+	}
+	if want, got := int64(42), final.A.GameID; want != got {
+		t.Errorf("game id, want %d got %d", want, got)
+	}
+	if b.Finished() {
+		t.Errorf("should not be finished")
+	}
+
+	results[ScoreKey("r3m1", "c4")] = 10
+	results[ScoreKey("r3m1", "c3")] = 11
+	b = tr.Bracket(results)
+	if b.Champion == nil || b.Champion.ChanID != "c3" {
+		t.Errorf("champion, want c3 got %v", b.Champion)
+	}
+}
+
+func TestQuestionSeed(t *testing.T) {
+	tr := newTournament(t, 2)
+	if tr.QuestionSeed(1) == tr.QuestionSeed(2) {
+		t.Errorf("rounds should have different questions")
+	}
+	if tr.QuestionSeed(1) < 0 {
+		t.Errorf("seed should be positive")
+	}
+}
+
+func pairs(matches []*Match) string {
+	var s []string
+	for _, m := range matches {
+		a, b := "?", "?"
+		if m.A != nil {
+			a = m.A.ChanID
+		}
+		if m.B != nil {
+			b = m.B.ChanID
+		}
+		s = append(s, a+" vs "+b)
+	}
+	return fmt.Sprint(s)
+}
@@ -0,0 +1,294 @@
+// Package webhook delivers game events to HTTP endpoints. Payloads are JSON
+// signed with HMAC-SHA256 of a shared secret, failed deliveries are retried
+// with exponential backoff and every attempt is recorded in a delivery log.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+)
+
+// Request headers sent with every delivery
+const (
+	HeaderSignature = "X-Fam100-Signature"
+	HeaderEvent     = "X-Fam100-Event"
+	HeaderDelivery  = "X-Fam100-Delivery"
+)
+
+// Available events
+const (
+	GameStarted   = "game.started"
+	RoundFinished = "round.finished"
+	GameFinished  = "game.finished"
+	AnswerCorrect = "answer.correct"
+)
+
+var (
+	deliveredCount = metrics.NewRegisteredCounter("webhook.delivered.count", metrics.DefaultRegistry)
+	failedCount    = metrics.NewRegisteredCounter("webhook.failed.count", metrics.DefaultRegistry)
+	droppedCount   = metrics.NewRegisteredCounter("webhook.dropped.count", metrics.DefaultRegistry)
+	deliveryTimer  = metrics.NewRegisteredTimer("webhook.delivery.ns", metrics.DefaultRegistry)
+)
+
+// Endpoint is a receiver of webhook
+type Endpoint struct {
+	URL    string
+	Secret string
+	Events []string // empty receives all events
+}
+
+// Validate returns an error if the endpoint can not receive signed payloads
+func (e Endpoint) Validate() error {
+	if e.URL == "" {
+		return errors.New("webhook url is empty")
+	}
+	if e.Secret == "" {
+		return errors.Errorf("webhook %s has no secret", e.URL)
+	}
+	return nil
+}
+
+// Accept returns whether the endpoint subscribes to the event
+func (e Endpoint) Accept(event string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, ev := range e.Events {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body of a webhook request
+type Payload struct {
+	ID     string      `json:"id"`
+	Event  string      `json:"event"`
+	GameID int64       `json:"gameID"`
+	ChanID string      `json:"chanID"`
+	At     time.Time   `json:"at"`
+	Data   interface{} `json:"data"`
+}
+
+// Delivery is one attempt to deliver a payload
+type Delivery struct {
+	ID       string        `json:"id"`
+	URL      string        `json:"url"`
+	Event    string        `json:"event"`
+	Attempt  int           `json:"attempt"`
+	Status   int           `json:"status"` // 0 if the request failed
+	Error    string        `json:"error,omitempty"`
+	At       time.Time     `json:"at"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Success returns whether the receiver accepted the payload
+func (d Delivery) Success() bool {
+	return d.Error == "" && d.Status >= 200 && d.Status < 300
+}
+
+// Log records delivery attempts
+type Log interface {
+	Record(d Delivery) error
+}
+
+// MemoryLog keeps the latest delivery attempts in memory
+type MemoryLog struct {
+	mu         sync.Mutex
+	size       int
+	deliveries []Delivery
+}
+
+// NewMemoryLog creates a log that keeps at most size attempts
+func NewMemoryLog(size int) *MemoryLog {
+	return &MemoryLog{size: size}
+}
+
+// Record implements Log
+func (l *MemoryLog) Record(d Delivery) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.deliveries = append(l.deliveries, d)
+	if len(l.deliveries) > l.size {
+		l.deliveries = l.deliveries[len(l.deliveries)-l.size:]
+	}
+	return nil
+}
+
+// Deliveries returns recorded attempts, oldest first
+func (l *MemoryLog) Deliveries() []Delivery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Delivery(nil), l.deliveries...)
+}
+
+// Sign returns the signature of body, it is sent in HeaderSignature
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of body, it is meant for receivers
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+type job struct {
+	endpoint Endpoint
+	payload  Payload
+}
+
+// Dispatcher sends payloads in the background
+type Dispatcher struct {
+	Client      *http.Client
+	Log         Log
+	MaxAttempts int
+	Backoff     time.Duration // delay before the first retry, doubled after every attempt
+	MaxBackoff  time.Duration
+
+	queue chan job
+}
+
+// NewDispatcher creates a dispatcher with a queue of the given size
+func NewDispatcher(queueSize int, log Log) *Dispatcher {
+	return &Dispatcher{
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		Log:         log,
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+		queue:       make(chan job, queueSize),
+	}
+}
+
+// Start runs workers that deliver queued payloads until ctx is done
+func (d *Dispatcher) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-d.queue:
+					d.deliver(ctx, j)
+				}
+			}
+		}()
+	}
+}
+
+// Send queues the payload for the endpoint, it never blocks. It returns false
+// if the endpoint is invalid or the queue is full
+func (d *Dispatcher) Send(e Endpoint, p Payload) bool {
+	if p.ID == "" {
+		p.ID = fmt.Sprintf("%d-%d", time.Now().UnixNano(), rand.Int31())
+	}
+	if p.At.IsZero() {
+		p.At = time.Now()
+	}
+	if err := e.Validate(); err != nil {
+		d.record(Delivery{ID: p.ID, URL: e.URL, Event: p.Event, At: time.Now(), Error: err.Error()})
+		return false
+	}
+
+	select {
+	case d.queue <- job{endpoint: e, payload: p}:
+		return true
+	default:
+		droppedCount.Inc(1)
+		return false
+	}
+}
+
+// deliver posts the payload and retries until it succeeded or MaxAttempts is reached
+func (d *Dispatcher) deliver(ctx context.Context, j job) {
+	// marshal in the worker, the sender may be a game loop
+	body, err := json.Marshal(j.payload)
+	if err != nil {
+		d.record(Delivery{ID: j.payload.ID, URL: j.endpoint.URL, Event: j.payload.Event, At: time.Now(), Error: err.Error()})
+		failedCount.Inc(1)
+		return
+	}
+
+	backoff := d.Backoff
+	for attempt := 1; attempt <= d.MaxAttempts; attempt++ {
+		dl, retry := d.post(ctx, j, body, attempt)
+		d.record(dl)
+		if dl.Success() {
+			deliveredCount.Inc(1)
+			return
+		}
+		if !retry || attempt == d.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if d.MaxBackoff > 0 && backoff > d.MaxBackoff {
+			backoff = d.MaxBackoff
+		}
+	}
+	failedCount.Inc(1)
+}
+
+// post sends one request, retry is false if the receiver rejected the payload
+func (d *Dispatcher) post(ctx context.Context, j job, body []byte, attempt int) (dl Delivery, retry bool) {
+	start := time.Now()
+	defer deliveryTimer.UpdateSince(start)
+
+	dl = Delivery{ID: j.payload.ID, URL: j.endpoint.URL, Event: j.payload.Event, Attempt: attempt, At: start}
+	req, err := http.NewRequest(http.MethodPost, j.endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		dl.Error = err.Error()
+		return dl, false
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, j.payload.Event)
+	req.Header.Set(HeaderDelivery, j.payload.ID)
+	req.Header.Set(HeaderSignature, Sign(j.endpoint.Secret, body))
+
+	resp, err := d.Client.Do(req)
+	dl.Duration = time.Since(start)
+	if err != nil {
+		dl.Error = errors.Wrap(err, "request failed").Error()
+		return dl, true
+	}
+	resp.Body.Close()
+	dl.Status = resp.StatusCode
+	if !dl.Success() {
+		dl.Error = resp.Status
+	}
+
+	// client errors won't be fixed by retrying, except throttling
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return dl, retry
+}
+
+func (d *Dispatcher) record(dl Delivery) {
+	if d.Log == nil {
+		return
+	}
+	// delivery log is best effort
+	_ = d.Log.Record(dl)
+}
@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDispatcher(t *testing.T) {
+	secret := "s3cret"
+	var calls int32
+	received := make(chan Payload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if !Verify(secret, body, r.Header.Get(HeaderSignature)) {
+			t.Errorf("invalid signature %q", r.Header.Get(HeaderSignature))
+		}
+		if want, got := GameFinished, r.Header.Get(HeaderEvent); want != got {
+			t.Errorf("event want %s got %s", want, got)
+		}
+
+		// fail the first attempt
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var p Payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Error(err)
+		}
+		received <- p
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log := NewMemoryLog(10)
+	d := NewDispatcher(10, log)
+	d.Backoff = time.Millisecond
+	d.Start(ctx, 1)
+
+	e := Endpoint{URL: srv.URL, Secret: secret, Events: []string{GameFinished}}
+	if !e.Accept(GameFinished) || e.Accept(AnswerCorrect) {
+		t.Fatalf("endpoint should only accept %s", GameFinished)
+	}
+	if !d.Send(e, Payload{Event: GameFinished, GameID: 1, ChanID: "chan", Data: map[string]int{"score": 10}}) {
+		t.Fatal("payload should be queued")
+	}
+
+	select {
+	case p := <-received:
+		if want, got := int64(1), p.GameID; want != got {
+			t.Errorf("gameID want %d got %d", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for webhook")
+	}
+
+	// the log is written after the response is received
+	var deliveries []Delivery
+	for i := 0; i < 100 && len(deliveries) < 2; i++ {
+		time.Sleep(time.Millisecond)
+		deliveries = log.Deliveries()
+	}
+	if want, got := 2, len(deliveries); want != got {
+		t.Fatalf("deliveries want %d got %d", want, got)
+	}
+	if want, got := http.StatusServiceUnavailable, deliveries[0].Status; want != got {
+		t.Errorf("first status want %d got %d", want, got)
+	}
+	if !deliveries[1].Success() || deliveries[1].Attempt != 2 {
+		t.Errorf("second attempt should succeed, got %+v", deliveries[1])
+	}
+}
+
+func TestDispatcherNoRetryOnClientError(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	log := NewMemoryLog(10)
+	d := NewDispatcher(10, log)
+	d.Backoff = time.Millisecond
+	d.deliver(context.Background(), job{endpoint: Endpoint{URL: srv.URL, Secret: "s3cret"}, payload: Payload{ID: "1", Event: GameStarted}})
+
+	if want, got := int32(1), atomic.LoadInt32(&calls); want != got {
+		t.Errorf("calls want %d got %d", want, got)
+	}
+	if want, got := 1, len(log.Deliveries()); want != got {
+		t.Errorf("deliveries want %d got %d", want, got)
+	}
+}
+
+func TestEndpointWithoutSecret(t *testing.T) {
+	e := Endpoint{URL: "http://localhost"}
+	if err := e.Validate(); err == nil {
+		t.Error("endpoint without secret should be invalid")
+	}
+
+	log := NewMemoryLog(10)
+	d := NewDispatcher(10, log)
+	if d.Send(e, Payload{Event: GameStarted}) {
+		t.Error("payload to endpoint without secret should be rejected")
+	}
+	if want, got := 1, len(log.Deliveries()); want != got {
+		t.Fatalf("deliveries want %d got %d", want, got)
+	}
+	if log.Deliveries()[0].Success() {
+		t.Error("rejected delivery should not succeed")
+	}
+}